@@ -3,7 +3,10 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
+	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydomain"
+	"aleksandersh.github.io/planning-poker-server/internal/oidc"
 	"aleksandersh.github.io/planning-poker-server/internal/server"
 	"github.com/gin-gonic/gin"
 )
@@ -12,6 +15,35 @@ const (
 	envAddress   = "POKER_ADDRESS"
 	envMode      = "POKER_MODE"
 	envModeDebug = "debug"
+	envStorage   = "POKER_STORAGE"
+
+	envSnapshot         = "POKER_SNAPSHOT"
+	envSnapshotInterval = "POKER_SNAPSHOT_INTERVAL"
+
+	defaultSnapshotInterval = time.Minute
+
+	envLinkSigningKey = "POKER_LINK_SIGNING_KEY"
+
+	envTokenTTL     = "POKER_TOKEN_TTL"
+	defaultTokenTTL = 7 * 24 * time.Hour
+	envAuthMode     = "POKER_AUTH_MODE"
+	envJWTKeys      = "POKER_JWT_KEYS"
+
+	envOIDCIssuer       = "POKER_OIDC_ISSUER"
+	envOIDCClientID     = "POKER_OIDC_CLIENT_ID"
+	envOIDCClientSecret = "POKER_OIDC_CLIENT_SECRET"
+	envOIDCRedirectURL  = "POKER_OIDC_REDIRECT_URL"
+	envOIDCClientURL    = "POKER_OIDC_CLIENT_URL"
+
+	envRoomTTL          = "POKER_ROOM_TTL"
+	envPlayerTTL        = "POKER_PLAYER_TTL"
+	envUserTTL          = "POKER_USER_TTL"
+	envActivityInterval = "POKER_ACTIVITY_INTERVAL"
+
+	defaultRoomTTL          = 24 * time.Hour
+	defaultPlayerTTL        = 2 * time.Hour
+	defaultUserTTL          = 7 * 24 * time.Hour
+	defaultActivityInterval = time.Minute
 )
 
 func main() {
@@ -24,7 +56,44 @@ func main() {
 	if !isDebug {
 		gin.SetMode(gin.ReleaseMode)
 	}
-	log.Printf("Start poker app (address=%s, isDebug=%t)", address, isDebug)
+	config := server.Config{
+		Address:          address,
+		Storage:          os.Getenv(envStorage),
+		SnapshotPath:     os.Getenv(envSnapshot),
+		SnapshotInterval: getDurationEnv(envSnapshotInterval, defaultSnapshotInterval),
+		LinkSigningKey:   []byte(os.Getenv(envLinkSigningKey)),
+		TokenTTL:         getDurationEnv(envTokenTTL, defaultTokenTTL),
+		AuthMode:         os.Getenv(envAuthMode),
+		JWTKeys:          os.Getenv(envJWTKeys),
+		OIDC: oidc.Config{
+			Issuer:       os.Getenv(envOIDCIssuer),
+			ClientID:     os.Getenv(envOIDCClientID),
+			ClientSecret: os.Getenv(envOIDCClientSecret),
+			RedirectURL:  os.Getenv(envOIDCRedirectURL),
+		},
+		OIDCClientURL: os.Getenv(envOIDCClientURL),
+		Activity: activitydomain.Config{
+			RoomTTL:   getDurationEnv(envRoomTTL, defaultRoomTTL),
+			PlayerTTL: getDurationEnv(envPlayerTTL, defaultPlayerTTL),
+			UserTTL:   getDurationEnv(envUserTTL, defaultUserTTL),
+			Interval:  getDurationEnv(envActivityInterval, defaultActivityInterval),
+		},
+	}
+	log.Printf("Start poker app (address=%s, isDebug=%t, storage=%s)", address, isDebug, config.Storage)
+
+	if err := server.Start(config); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	server.Start(address)
+func getDurationEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatal("variable $" + name + " must be a positive duration")
+	}
+	return duration
 }
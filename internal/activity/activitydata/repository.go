@@ -7,28 +7,35 @@ import (
 
 type Repository struct {
 	mutex   sync.Mutex
+	now     func() time.Time
 	rooms   map[string]time.Time
 	users   map[string]time.Time
-	players map[playerKey]time.Time
+	players map[PlayerKey]time.Time
 }
 
-type playerKey struct {
+type PlayerKey struct {
 	RoomID string
 	UserID string
 }
 
 func NewRepository() *Repository {
+	return NewRepositoryWithClock(time.Now)
+}
+
+// NewRepositoryWithClock creates a repository which records the activity at the time given by the clock.
+func NewRepositoryWithClock(now func() time.Time) *Repository {
 	return &Repository{
+		now:     now,
 		rooms:   make(map[string]time.Time),
 		users:   make(map[string]time.Time),
-		players: make(map[playerKey]time.Time),
+		players: make(map[PlayerKey]time.Time),
 	}
 }
 
 func (r *Repository) AddUserActivity(userID string) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	now := time.Now()
+	now := r.now()
 	r.users[userID] = now
 }
 
@@ -36,12 +43,84 @@ func (r *Repository) AddPlayerActivity(roomID string, userID string) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	now := time.Now()
+	now := r.now()
 	r.rooms[roomID] = now
 	r.users[userID] = now
-	pk := playerKey{RoomID: roomID, UserID: userID}
+	pk := PlayerKey{RoomID: roomID, UserID: userID}
 	r.players[pk] = now
 }
 
+// DeleteActivity deletes the activity of the user in the room.
 func (r *Repository) DeleteActivity(userID string, roomID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.players, PlayerKey{RoomID: roomID, UserID: userID})
+}
+
+// DeleteRoomActivity deletes the activity of the room and all its players.
+func (r *Repository) DeleteRoomActivity(roomID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.rooms, roomID)
+	for pk := range r.players {
+		if pk.RoomID == roomID {
+			delete(r.players, pk)
+		}
+	}
+}
+
+func (r *Repository) DeleteUserActivity(userID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.users, userID)
+}
+
+// DeleteAllUserActivity deletes the activity of the user together with the activity of the user in all rooms.
+func (r *Repository) DeleteAllUserActivity(userID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.users, userID)
+	for pk := range r.players {
+		if pk.UserID == userID {
+			delete(r.players, pk)
+		}
+	}
+}
+
+// GetIdleRooms returns IDs of rooms with the last activity before the given time.
+func (r *Repository) GetIdleRooms(before time.Time) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return getIdleKeys(r.rooms, before)
+}
+
+// GetIdlePlayers returns players with the last activity in the room before the given time.
+func (r *Repository) GetIdlePlayers(before time.Time) []PlayerKey {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return getIdleKeys(r.players, before)
+}
+
+// GetIdleUsers returns IDs of users with the last activity before the given time.
+func (r *Repository) GetIdleUsers(before time.Time) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return getIdleKeys(r.users, before)
+}
+
+func getIdleKeys[K comparable](activity map[K]time.Time, before time.Time) []K {
+	var keys []K
+	for key, lastActivity := range activity {
+		if lastActivity.Before(before) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
 }
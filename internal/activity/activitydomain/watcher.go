@@ -0,0 +1,113 @@
+package activitydomain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydata"
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+)
+
+type Config struct {
+	RoomTTL   time.Duration
+	PlayerTTL time.Duration
+	UserTTL   time.Duration
+	// Interval is a period of checking the activity.
+	Interval time.Duration
+}
+
+type RoomsRepository interface {
+	GetRooms() []rooms.Room
+	RemoveRoom(roomID string) error
+	RemovePlayer(roomID string, userID string) error
+}
+
+type UsersRepository interface {
+	Export() ([]users.User, []users.Session, []users.Identity)
+	HasIdentity(userID string) bool
+	DeleteUser(userID string) error
+	DeleteExpiredSessions(now time.Time) error
+}
+
+// Watcher expires idle rooms, players and users together with expired sessions, so their resources are released.
+type Watcher struct {
+	config             Config
+	activityRepository *activitydata.Repository
+	roomsRepository    RoomsRepository
+	usersRepository    UsersRepository
+}
+
+func NewWatcher(config Config, activityRepository *activitydata.Repository, roomsRepository RoomsRepository, usersRepository UsersRepository) *Watcher {
+	return &Watcher{
+		config:             config,
+		activityRepository: activityRepository,
+		roomsRepository:    roomsRepository,
+		usersRepository:    usersRepository,
+	}
+}
+
+// Run checks the activity on the interval until the context is done. Rooms, players and users
+// which exist before the start, like the ones restored from a storage, are considered active at the start.
+func (w *Watcher) Run(ctx context.Context) {
+	for _, room := range w.roomsRepository.GetRooms() {
+		for _, player := range room.Players {
+			w.activityRepository.AddPlayerActivity(room.ID, player.UserID)
+		}
+	}
+	usersList, _, _ := w.usersRepository.Export()
+	for _, user := range usersList {
+		w.activityRepository.AddUserActivity(user.ID)
+	}
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.expire(now)
+		}
+	}
+}
+
+func (w *Watcher) expire(now time.Time) {
+	for _, roomID := range w.activityRepository.GetIdleRooms(now.Add(-w.config.RoomTTL)) {
+		err := w.roomsRepository.RemoveRoom(roomID)
+		if err != nil && !errors.Is(err, rooms.ErrRoomNotFound) {
+			log.Println(fmt.Errorf("failed to remove idle room: %w", err))
+			continue
+		}
+		w.activityRepository.DeleteRoomActivity(roomID)
+	}
+
+	for _, player := range w.activityRepository.GetIdlePlayers(now.Add(-w.config.PlayerTTL)) {
+		err := w.roomsRepository.RemovePlayer(player.RoomID, player.UserID)
+		if err != nil && !errors.Is(err, rooms.ErrRoomNotFound) && !errors.Is(err, rooms.ErrForbidden) {
+			log.Println(fmt.Errorf("failed to remove idle player: %w", err))
+			continue
+		}
+		w.activityRepository.DeleteActivity(player.UserID, player.RoomID)
+	}
+
+	for _, userID := range w.activityRepository.GetIdleUsers(now.Add(-w.config.UserTTL)) {
+		// users of identity providers are kept, so they get the same identity on the next login
+		if w.usersRepository.HasIdentity(userID) {
+			w.activityRepository.DeleteUserActivity(userID)
+			continue
+		}
+		if err := w.usersRepository.DeleteUser(userID); err != nil {
+			log.Println(fmt.Errorf("failed to delete idle user: %w", err))
+			continue
+		}
+		w.activityRepository.DeleteUserActivity(userID)
+	}
+
+	if err := w.usersRepository.DeleteExpiredSessions(now); err != nil {
+		log.Println(fmt.Errorf("failed to delete expired sessions: %w", err))
+	}
+}
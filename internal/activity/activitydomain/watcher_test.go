@@ -0,0 +1,128 @@
+package activitydomain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydata"
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdata"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+	"aleksandersh.github.io/planning-poker-server/internal/users/usersdata"
+)
+
+var testConfig = Config{
+	RoomTTL:   time.Hour,
+	PlayerTTL: 10 * time.Minute,
+	UserTTL:   24 * time.Hour,
+	Interval:  time.Minute,
+}
+
+// testClock is the time of the recorded activity, tests move it forward.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestWatcher(t *testing.T) (*Watcher, *testClock, *activitydata.Repository, *roomsdata.Repository, *usersdata.Repository) {
+	t.Helper()
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	activityRepository := activitydata.NewRepositoryWithClock(clock.Now)
+	roomsRepository := roomsdata.NewRepo()
+	usersRepository := usersdata.NewRepo()
+	return NewWatcher(testConfig, activityRepository, roomsRepository, usersRepository), clock, activityRepository, roomsRepository, usersRepository
+}
+
+func TestWatcherExpiresIdleRooms(t *testing.T) {
+	w, clock, activityRepository, roomsRepository, _ := newTestWatcher(t)
+	owner := users.User{ID: "owner", Name: "Owner"}
+	idle, err := roomsRepository.Create(owner, "Idle", false, rooms.DefaultDeck(), rooms.AutoReveal{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, err := roomsRepository.Create(owner, "Active", false, rooms.DefaultDeck(), rooms.AutoReveal{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	activityRepository.AddPlayerActivity(idle.ID, owner.ID)
+	clock.now = clock.now.Add(testConfig.RoomTTL / 2)
+	activityRepository.AddPlayerActivity(active.ID, owner.ID)
+
+	clock.now = clock.now.Add(testConfig.RoomTTL/2 + time.Second)
+	w.expire(clock.now)
+	if _, err := roomsRepository.Get(owner.ID, active.ID); err != nil {
+		t.Errorf("Get() of the room active before the TTL error = %v, want nil", err)
+	}
+	if _, err := roomsRepository.Get(owner.ID, idle.ID); err == nil {
+		t.Errorf("Get() of the room idle for the TTL succeeded, want it removed")
+	}
+
+	clock.now = clock.now.Add(testConfig.RoomTTL)
+	w.expire(clock.now)
+	if _, err := roomsRepository.Get(owner.ID, active.ID); !errors.Is(err, rooms.ErrRoomNotFound) {
+		t.Errorf("Get() of the room idle later error = %v, want %v", err, rooms.ErrRoomNotFound)
+	}
+}
+
+func TestWatcherExpiresIdlePlayers(t *testing.T) {
+	w, clock, activityRepository, roomsRepository, _ := newTestWatcher(t)
+	owner := users.User{ID: "owner", Name: "Owner"}
+	player := users.User{ID: "player", Name: "Player"}
+	room, err := roomsRepository.Create(owner, "Room", false, rooms.DefaultDeck(), rooms.AutoReveal{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := roomsRepository.Join(player, room.ID, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	activityRepository.AddPlayerActivity(room.ID, owner.ID)
+	activityRepository.AddPlayerActivity(room.ID, player.ID)
+
+	clock.now = clock.now.Add(testConfig.PlayerTTL - time.Second)
+	w.expire(clock.now)
+	if room, _ := roomsRepository.Get(owner.ID, room.ID); len(room.Players) != 2 {
+		t.Fatalf("players before the TTL = %v, want both players", room.Players)
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	activityRepository.AddPlayerActivity(room.ID, owner.ID)
+	clock.now = clock.now.Add(time.Second)
+	w.expire(clock.now)
+	room, err = roomsRepository.Get(owner.ID, room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(room.Players) != 1 || room.Players[0].UserID != owner.ID {
+		t.Errorf("players after the TTL = %v, want only the owner", room.Players)
+	}
+}
+
+func TestWatcherExpiresIdleUsers(t *testing.T) {
+	w, clock, activityRepository, _, usersRepository := newTestWatcher(t)
+	guest, err := usersRepository.CreateUser(users.User{Name: "Guest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	member, err := usersRepository.ResolveIdentity("https://issuer.example", "subject", "Member")
+	if err != nil {
+		t.Fatal(err)
+	}
+	activityRepository.AddUserActivity(guest.ID)
+	activityRepository.AddUserActivity(member.ID)
+
+	clock.now = clock.now.Add(testConfig.UserTTL + time.Second)
+	w.expire(clock.now)
+	if _, err := usersRepository.GetUser(guest.ID); !errors.Is(err, users.ErrUserNotFound) {
+		t.Errorf("GetUser() of the idle guest error = %v, want %v", err, users.ErrUserNotFound)
+	}
+	if _, err := usersRepository.GetUser(member.ID); err != nil {
+		t.Errorf("GetUser() of the idle user with an identity error = %v, want nil", err)
+	}
+	if idle := activityRepository.GetIdleUsers(clock.now); len(idle) != 0 {
+		t.Errorf("GetIdleUsers() = %v, want the activity of expired users deleted", idle)
+	}
+}
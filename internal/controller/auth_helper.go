@@ -26,6 +26,21 @@ func NewAuthHelper(usersService *usersdomain.Service) *AuthHelper {
 
 func (h *AuthHelper) ResolveUser(c *gin.Context) (users.User, bool) {
 	accessToken, err := h.getAccessToken(c)
+	return h.resolveUser(c, accessToken, err)
+}
+
+// ResolveStreamUserID resolves the user of a streaming request. Browser WebSocket and EventSource clients
+// can't set headers, so the access token is also accepted from the "access_token" query parameter.
+func (h *AuthHelper) ResolveStreamUserID(c *gin.Context) (string, bool) {
+	accessToken, err := h.getAccessToken(c)
+	if queryToken := c.Query("access_token"); err != nil && len(queryToken) > 0 {
+		accessToken, err = queryToken, nil
+	}
+	user, ok := h.resolveUser(c, accessToken, err)
+	return user.ID, ok
+}
+
+func (h *AuthHelper) resolveUser(c *gin.Context, accessToken string, err error) (users.User, bool) {
 	if err != nil {
 		log.Println(fmt.Errorf("authorization failed: %w", err))
 		c.AbortWithStatus(http.StatusUnauthorized)
@@ -42,6 +57,25 @@ func (h *AuthHelper) ResolveUser(c *gin.Context) (users.User, bool) {
 	return user, true
 }
 
+// ResolveSession resolves the session of the access token, expired and revoked tokens are rejected.
+func (h *AuthHelper) ResolveSession(c *gin.Context) (users.Session, bool) {
+	accessToken, err := h.getAccessToken(c)
+	if err != nil {
+		log.Println(fmt.Errorf("authorization failed: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return users.Session{}, false
+	}
+
+	session, err := h.usersService.ResolveSession(accessToken)
+	if err != nil {
+		log.Println(fmt.Errorf("authorization failed: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return users.Session{}, false
+	}
+
+	return session, true
+}
+
 func (h *AuthHelper) ResolveUserID(c *gin.Context) (string, bool) {
 	user, ok := h.ResolveUser(c)
 	return user.ID, ok
@@ -2,20 +2,72 @@ package controller
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"time"
 
 	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
 	"github.com/gin-gonic/gin"
 )
 
 func handleRoomsError(c *gin.Context, err error) {
-	if errors.Is(err, rooms.ErrRoomNotFound) {
+	if errors.Is(err, rooms.ErrRoomNotFound) || errors.Is(err, rooms.ErrInviteCodeNotFound) || errors.Is(err, rooms.ErrPlayerNotFound) ||
+		errors.Is(err, rooms.ErrGameNotFound) {
 		c.AbortWithStatus(http.StatusNotFound)
 	} else if errors.Is(err, rooms.ErrForbidden) {
 		c.AbortWithStatus(http.StatusForbidden)
+	} else if errors.Is(err, rooms.ErrUnknownRole) || errors.Is(err, rooms.ErrInvalidDeck) || errors.Is(err, rooms.ErrInvalidCard) ||
+		errors.Is(err, rooms.ErrInvalidAutoReveal) || errors.Is(err, rooms.ErrInvalidTimer) {
+		c.AbortWithStatus(http.StatusBadRequest)
+	} else if errors.Is(err, rooms.ErrIllegalGameStatus) {
+		c.AbortWithStatus(http.StatusConflict)
 	} else if errors.Is(err, rooms.ErrLimitExceeded) {
 		c.AbortWithStatus(http.StatusTooManyRequests)
 	} else {
+		log.Println(fmt.Errorf("rooms request failed: %w", err))
 		c.AbortWithStatus(http.StatusInternalServerError)
 	}
 }
+
+func handleUsersError(c *gin.Context, err error) {
+	if errors.Is(err, users.ErrSessionNotFound) || errors.Is(err, users.ErrUserNotFound) {
+		c.AbortWithStatus(http.StatusNotFound)
+	} else if errors.Is(err, users.ErrAccessTokenNotFound) || errors.Is(err, users.ErrAccessTokenExpired) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+	} else if errors.Is(err, users.ErrInvalidLoginState) || errors.Is(err, users.ErrInvalidProfile) {
+		c.AbortWithStatus(http.StatusBadRequest)
+	} else if errors.Is(err, users.ErrLoginFailed) {
+		log.Println(fmt.Errorf("users request failed: %w", err))
+		c.AbortWithStatus(http.StatusUnauthorized)
+	} else if errors.Is(err, users.ErrSessionsNotSupported) {
+		c.AbortWithStatus(http.StatusNotImplemented)
+	} else {
+		log.Println(fmt.Errorf("users request failed: %w", err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
+// timeOrNil makes zero times encoded as JSON nulls.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func stringOrNil(s string) *string {
+	if len(s) == 0 {
+		return nil
+	}
+	return &s
+}
+
+// emptyIfNil makes nil slices encoded as empty JSON arrays.
+func emptyIfNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"net/http"
+	"time"
 
 	"aleksandersh.github.io/planning-poker-server/internal/rooms"
 	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdomain"
@@ -14,19 +15,35 @@ type GamesController struct {
 }
 
 type gamePostRequest struct {
-	RoomID string `json:"room_id"`
-	Name   string `json:"name"`
+	RoomID     string             `json:"room_id"`
+	Name       string             `json:"name"`
+	Deck       *deckRequest       `json:"deck"`
+	AutoReveal *autoRevealRequest `json:"auto_reveal"`
 }
 
+// cardPostRequest takes the card value, the score is kept for clients which don't know decks.
 type cardPostRequest struct {
-	Score int `json:"score"`
+	Card  string  `json:"card"`
+	Score float64 `json:"score"`
+}
+
+// timerRequest takes a duration like "2m".
+type timerRequest struct {
+	Duration string `json:"duration" binding:"required"`
+}
+
+// finalEstimatePutRequest clears the final estimate if the value is empty.
+type finalEstimatePutRequest struct {
+	FinalEstimate string `json:"final_estimate"`
 }
 
 type gameDto struct {
-	ID     string `json:"id"`
-	RoomID string `json:"room_id"`
-	Name   string `json:"name"`
-	Status string `json:"status"`
+	ID            string     `json:"id"`
+	RoomID        string     `json:"room_id"`
+	Name          string     `json:"name"`
+	Status        string     `json:"status"`
+	Deadline      *time.Time `json:"deadline"`
+	FinalEstimate *string    `json:"final_estimate"`
 }
 
 func NewGamesController(authHelper *AuthHelper, gamesService *roomsdomain.GamesService) *GamesController {
@@ -42,7 +59,26 @@ func (gc *GamesController) Post(c *gin.Context) {
 	request := gamePostRequest{Name: ""}
 	c.ShouldBindJSON(&request)
 
-	game, err := gc.gamesService.Create(userID, request.RoomID, request.Name)
+	var deck rooms.Deck
+	if request.Deck != nil {
+		var err error
+		if deck, err = rooms.NewDeck(request.Deck.Type, request.Deck.Cards); err != nil {
+			handleRoomsError(c, err)
+			return
+		}
+	}
+
+	var autoReveal *rooms.AutoReveal = nil
+	if request.AutoReveal != nil {
+		value, err := parseAutoReveal(*request.AutoReveal)
+		if err != nil {
+			handleRoomsError(c, err)
+			return
+		}
+		autoReveal = &value
+	}
+
+	game, err := gc.gamesService.Create(userID, request.RoomID, request.Name, deck, autoReveal)
 	if err != nil {
 		handleRoomsError(c, err)
 		return
@@ -91,10 +127,13 @@ func (gc *GamesController) SendCard(c *gin.Context) {
 
 	gameID := c.Param("game_id")
 
-	request := cardPostRequest{Score: 0}
+	request := cardPostRequest{Card: "", Score: 0}
 	c.ShouldBindJSON(&request)
+	if len(request.Card) == 0 {
+		request.Card = rooms.LegacyScoreValue(request.Score)
+	}
 
-	game, err := gc.gamesService.SendCard(userID, gameID, request.Score)
+	game, err := gc.gamesService.SendCard(userID, gameID, request.Card)
 	if err != nil {
 		handleRoomsError(c, err)
 		return
@@ -120,11 +159,121 @@ func (gc *GamesController) DropCard(c *gin.Context) {
 	c.JSON(http.StatusOK, mapGameToDto(game))
 }
 
+// StartRound archives the current round of the completed game and starts a new one.
+func (gc *GamesController) StartRound(c *gin.Context) {
+	userID, ok := gc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	game, err := gc.gamesService.StartRound(userID, c.Param("game_id"))
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapGameToDto(game))
+}
+
+// PutFinalEstimate sets the final estimate of the completed game chosen by the facilitator.
+func (gc *GamesController) PutFinalEstimate(c *gin.Context) {
+	userID, ok := gc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	var request finalEstimatePutRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	game, err := gc.gamesService.SetFinalEstimate(userID, c.Param("game_id"), request.FinalEstimate)
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapGameToDto(game))
+}
+
+// StartTimer starts the voting timer of the game, the game is completed when it expires.
+func (gc *GamesController) StartTimer(c *gin.Context) {
+	userID, ok := gc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	duration, ok := requireTimerDuration(c)
+	if !ok {
+		return
+	}
+
+	game, err := gc.gamesService.StartTimer(userID, c.Param("game_id"), duration)
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapGameToDto(game))
+}
+
+func (gc *GamesController) ExtendTimer(c *gin.Context) {
+	userID, ok := gc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	duration, ok := requireTimerDuration(c)
+	if !ok {
+		return
+	}
+
+	game, err := gc.gamesService.ExtendTimer(userID, c.Param("game_id"), duration)
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapGameToDto(game))
+}
+
+func (gc *GamesController) CancelTimer(c *gin.Context) {
+	userID, ok := gc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	game, err := gc.gamesService.CancelTimer(userID, c.Param("game_id"))
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapGameToDto(game))
+}
+
+func requireTimerDuration(c *gin.Context) (time.Duration, bool) {
+	var request timerRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return 0, false
+	}
+	duration, err := time.ParseDuration(request.Duration)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return 0, false
+	}
+	return duration, true
+}
+
 func mapGameToDto(game rooms.Game) gameDto {
 	return gameDto{
-		ID:     game.ID,
-		RoomID: game.RoomID,
-		Name:   game.Name,
-		Status: game.Status,
+		ID:            game.ID,
+		RoomID:        game.RoomID,
+		Name:          game.Name,
+		Status:        game.Status,
+		Deadline:      timeOrNil(game.Deadline),
+		FinalEstimate: stringOrNil(game.FinalEstimate),
 	}
 }
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdomain"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultInviteLinkTTL = 2 * time.Hour
+	maxInviteLinkTTL     = 7 * 24 * time.Hour
+)
+
+type InviteCodesController struct {
+	authHelper   *AuthHelper
+	roomsService *roomsdomain.RoomsService
+}
+
+type inviteCodePostRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   int        `json:"max_uses"`
+}
+
+type inviteLinkPostRequest struct {
+	ExpiresIn string `json:"expires_in"`
+	Role      string `json:"role"`
+}
+
+type inviteLinkDto struct {
+	Token     string    `json:"token"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type inviteCodeDto struct {
+	Code      string     `json:"code"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+}
+
+func NewInviteCodesController(authHelper *AuthHelper, roomsService *roomsdomain.RoomsService) *InviteCodesController {
+	return &InviteCodesController{authHelper: authHelper, roomsService: roomsService}
+}
+
+func (ic *InviteCodesController) Post(c *gin.Context) {
+	userID, ok := ic.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	roomID, ok := requireRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	request := inviteCodePostRequest{ExpiresAt: nil, MaxUses: 0}
+	c.ShouldBindJSON(&request)
+
+	expiresAt := time.Time{}
+	if request.ExpiresAt != nil {
+		expiresAt = *request.ExpiresAt
+	}
+	if request.MaxUses < 0 || (!expiresAt.IsZero() && !expiresAt.After(time.Now())) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	code, err := ic.roomsService.CreateInviteCode(userID, roomID, expiresAt, request.MaxUses)
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, mapInviteCodeToDto(code))
+}
+
+func (ic *InviteCodesController) GetAll(c *gin.Context) {
+	userID, ok := ic.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	roomID, ok := requireRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	codes, err := ic.roomsService.GetInviteCodes(userID, roomID)
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	response := make([]inviteCodeDto, 0, len(codes))
+	for _, code := range codes {
+		response = append(response, mapInviteCodeToDto(code))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (ic *InviteCodesController) Delete(c *gin.Context) {
+	userID, ok := ic.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	roomID, ok := requireRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := ic.roomsService.RevokeInviteCode(userID, roomID, c.Param("code")); err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// PostLink creates a signed invite link token, it is passed to the join request as the "invite-link" query parameter.
+func (ic *InviteCodesController) PostLink(c *gin.Context) {
+	userID, ok := ic.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	roomID, ok := requireRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	request := inviteLinkPostRequest{ExpiresIn: "", Role: ""}
+	c.ShouldBindJSON(&request)
+
+	ttl := defaultInviteLinkTTL
+	if len(request.ExpiresIn) > 0 {
+		value, err := time.ParseDuration(request.ExpiresIn)
+		if err != nil || value <= 0 || value > maxInviteLinkTTL {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		ttl = value
+	}
+
+	link, err := ic.roomsService.CreateInviteLink(userID, roomID, ttl, request.Role)
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, inviteLinkDto{Token: link.Token, Role: link.Role, ExpiresAt: link.ExpiresAt})
+}
+
+func mapInviteCodeToDto(code rooms.InviteCode) inviteCodeDto {
+	var expiresAt *time.Time = nil
+	if !code.ExpiresAt.IsZero() {
+		expiresAt = &code.ExpiresAt
+	}
+	return inviteCodeDto{
+		Code:      code.Code,
+		CreatedAt: code.CreatedAt,
+		ExpiresAt: expiresAt,
+		MaxUses:   code.MaxUses,
+		Uses:      code.Uses,
+	}
+}
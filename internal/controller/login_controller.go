@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+	"aleksandersh.github.io/planning-poker-server/internal/users/usersdomain"
+	"github.com/gin-gonic/gin"
+)
+
+// loginBindingCookie keeps the value binding the login state to the browser which started the login.
+const loginBindingCookie = "poker_oidc_binding"
+
+// Errors of the login passed to the client page.
+const (
+	loginErrorRejected     = "login_rejected"
+	loginErrorInvalidState = "invalid_login_state"
+	loginErrorFailed       = "login_failed"
+)
+
+type LoginController struct {
+	loginService *usersdomain.LoginService
+	// clientURL is a page of the client the callback redirects to.
+	clientURL string
+}
+
+func NewLoginController(loginService *usersdomain.LoginService, clientURL string) *LoginController {
+	return &LoginController{loginService: loginService, clientURL: clientURL}
+}
+
+// Start redirects to the login page of the identity provider.
+func (lc *LoginController) Start(c *gin.Context) {
+	url, binding, err := lc.loginService.Start(c.Request.Context())
+	if err != nil {
+		handleUsersError(c, err)
+		return
+	}
+
+	// The cookie must be sent with the redirect from the provider, which is a cross-site navigation.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(loginBindingCookie, binding, 0, "/v1/auth/oidc", "", isSecureRequest(c), true)
+	c.Redirect(http.StatusFound, url)
+}
+
+// Callback completes the login with the authorization code the identity provider redirects with.
+// The callback is a navigation of the browser, so it redirects to the client page with the access token
+// or the error in the fragment, which isn't sent to servers.
+func (lc *LoginController) Callback(c *gin.Context) {
+	if providerError := c.Query("error"); providerError != "" {
+		log.Printf("login rejected by the identity provider: %s", providerError)
+		lc.redirectToClient(c, url.Values{"error": {loginErrorRejected}})
+		return
+	}
+
+	code := c.Query("code")
+	binding, err := c.Cookie(loginBindingCookie)
+	if code == "" || err != nil {
+		lc.redirectToClient(c, url.Values{"error": {loginErrorInvalidState}})
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(loginBindingCookie, "", -1, "/v1/auth/oidc", "", isSecureRequest(c), true)
+
+	user, session, err := lc.loginService.Complete(c.Request.Context(), code, c.Query("state"), binding)
+	if errors.Is(err, users.ErrInvalidLoginState) {
+		lc.redirectToClient(c, url.Values{"error": {loginErrorInvalidState}})
+		return
+	}
+	if err != nil {
+		log.Println(fmt.Errorf("login callback failed: %w", err))
+		lc.redirectToClient(c, url.Values{"error": {loginErrorFailed}})
+		return
+	}
+
+	lc.redirectToClient(c, url.Values{
+		"user_id":      {user.ID},
+		"access_token": {session.AccessToken},
+		"expires_at":   {session.ExpiresAt.Format(time.RFC3339)},
+	})
+}
+
+func (lc *LoginController) redirectToClient(c *gin.Context, fragment url.Values) {
+	c.Redirect(http.StatusFound, lc.clientURL+"#"+fragment.Encode())
+}
+
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydata"
+	"aleksandersh.github.io/planning-poker-server/internal/oidc"
+	"aleksandersh.github.io/planning-poker-server/internal/oidc/oidctest"
+	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdata"
+	"aleksandersh.github.io/planning-poker-server/internal/users/usersdata"
+	"aleksandersh.github.io/planning-poker-server/internal/users/usersdomain"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/signutils"
+	"github.com/gin-gonic/gin"
+)
+
+const testClientURL = "http://localhost/login"
+
+func newTestLoginRouter(t *testing.T) http.Handler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	fake := oidctest.NewProvider()
+	t.Cleanup(fake.Close)
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       fake.Issuer(),
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "http://localhost/v1/auth/oidc/callback",
+	})
+	us := usersdomain.NewService(usersdata.NewRepo(), activitydata.NewRepository(), roomsdata.NewRepo(), time.Hour, nil)
+	lc := NewLoginController(usersdomain.NewLoginService(us, provider, signutils.NewSigner(signutils.GenerateKey())), testClientURL)
+
+	router := gin.New()
+	router.GET("/v1/auth/oidc/login", lc.Start)
+	router.GET("/v1/auth/oidc/callback", lc.Callback)
+	return router
+}
+
+// startLogin starts the login and follows the redirect of the provider, it returns the callback URL and the cookie.
+func startLogin(t *testing.T, router http.Handler) (string, *http.Cookie) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/auth/oidc/login", nil))
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want %d", recorder.Code, http.StatusFound)
+	}
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != loginBindingCookie {
+		t.Fatalf("login cookies = %v, want %s", cookies, loginBindingCookie)
+	}
+	if !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie HttpOnly = %v, SameSite = %v", cookies[0].HttpOnly, cookies[0].SameSite)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	response, err := client.Get(recorder.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	callback, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return callback.RequestURI(), cookies[0]
+}
+
+func TestLoginCallbackRedirectsToClient(t *testing.T) {
+	router := newTestLoginRouter(t)
+	callback, cookie := startLogin(t, router)
+	_, otherCookie := startLogin(t, router)
+
+	tests := []struct {
+		name      string
+		callback  string
+		cookie    *http.Cookie
+		wantError string
+	}{
+		{name: "without the cookie", callback: callback, wantError: loginErrorInvalidState},
+		{name: "with the cookie of another login", callback: callback, cookie: otherCookie, wantError: loginErrorInvalidState},
+		{name: "rejected by the provider", callback: "/v1/auth/oidc/callback?error=access_denied", cookie: cookie, wantError: loginErrorRejected},
+		{name: "with the cookie of the login", callback: callback, cookie: cookie},
+		{name: "used again", callback: callback, cookie: cookie, wantError: loginErrorFailed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, test.callback, nil)
+			if test.cookie != nil {
+				request.AddCookie(test.cookie)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != http.StatusFound {
+				t.Fatalf("callback status = %d, want %d", recorder.Code, http.StatusFound)
+			}
+
+			location, err := url.Parse(recorder.Header().Get("Location"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if location.Scheme+"://"+location.Host+location.Path != testClientURL || location.RawQuery != "" {
+				t.Errorf("redirect = %s, want the client URL with a fragment", location)
+			}
+			fragment, err := url.ParseQuery(location.Fragment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fragment.Get("error") != test.wantError {
+				t.Errorf("error = %q, want %q", fragment.Get("error"), test.wantError)
+			}
+			hasToken := fragment.Get("access_token") != "" && fragment.Get("user_id") != "" && fragment.Get("expires_at") != ""
+			if hasToken != (test.wantError == "") {
+				t.Errorf("fragment = %v, want the access token only on success", fragment)
+			}
+		})
+	}
+}
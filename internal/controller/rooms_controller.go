@@ -2,21 +2,45 @@ package controller
 
 import (
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"aleksandersh.github.io/planning-poker-server/internal/rooms"
 	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdomain"
 	"github.com/gin-gonic/gin"
 )
 
+const maxStateWait = 60 * time.Second
+
 type RoomsController struct {
 	authHelper   *AuthHelper
 	roomsService *roomsdomain.RoomsService
 }
 
 type roomsPostRequest struct {
-	Name               string `json:"name"`
-	InviteCodeRequired bool   `json:"invite_code_required"`
+	Name               string             `json:"name"`
+	InviteCodeRequired bool               `json:"invite_code_required"`
+	Deck               *deckRequest       `json:"deck"`
+	AutoReveal         *autoRevealRequest `json:"auto_reveal"`
+}
+
+// autoRevealRequest enables the auto reveal, the delay is a duration like "10s".
+type autoRevealRequest struct {
+	Enabled bool   `json:"enabled"`
+	Delay   string `json:"delay"`
+}
+
+type autoRevealDto struct {
+	Enabled bool   `json:"enabled"`
+	Delay   string `json:"delay"`
+}
+
+// deckRequest chooses a deck by its type, the cards are given only for the custom deck.
+type deckRequest struct {
+	Type  string   `json:"type"`
+	Cards []string `json:"cards"`
 }
 
 type roomDto struct {
@@ -30,38 +54,125 @@ type roomStateDto struct {
 	Name        string          `json:"name"`
 	Owner       string          `json:"owner"`
 	Commit      string          `json:"commit"`
+	Sequence    int             `json:"sequence"`
+	Deck        deckDto         `json:"deck"`
+	AutoReveal  autoRevealDto   `json:"auto_reveal"`
 	Players     []playerDto     `json:"players"`
 	CurrentGame *currentGameDto `json:"current_game"`
 	GameResults []gameResultDto `json:"game_results"`
 }
 
+type roomEventDto struct {
+	Sequence      int                    `json:"sequence"`
+	Type          string                 `json:"type"`
+	UserID        string                 `json:"user_id"`
+	CreatedAt     time.Time              `json:"created_at"`
+	FinalEstimate *finalEstimateEventDto `json:"final_estimate,omitempty"`
+}
+
+// finalEstimateEventDto is the final estimate chosen by the final_estimate_changed event, the value is null if it is cleared.
+type finalEstimateEventDto struct {
+	GameID string  `json:"game_id"`
+	Value  *string `json:"value"`
+}
+
 type playerDto struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Color string `json:"color"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Color      string `json:"color"`
+	Avatar     string `json:"avatar"`
+	Role       string `json:"role"`
+	IsObserver bool   `json:"is_observer"`
+	HasVoted   bool   `json:"has_voted"`
+}
+
+type ownerPutRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+type playerRolePutRequest struct {
+	Role string `json:"role" binding:"required"`
 }
 
 type currentGameDto struct {
-	ID              string    `json:"id"`
-	Name            string    `json:"name"`
-	Status          string    `json:"status"`
-	MaxScore        int       `json:"max_score"`
-	AverageScore    int       `json:"average_score"`
-	IsCardsRevealed bool      `json:"is_card_revealed"`
-	Cards           []cardDto `json:"cards"`
+	ID                   string                   `json:"id"`
+	Name                 string                   `json:"name"`
+	Status               string                   `json:"status"`
+	Deck                 *deckDto                 `json:"deck"`
+	AutoReveal           autoRevealDto            `json:"auto_reveal"`
+	RevealAt             *time.Time               `json:"reveal_at"`
+	Deadline             *time.Time               `json:"deadline"`
+	ServerTime           time.Time                `json:"server_time"`
+	MaxScore             float64                  `json:"max_score"`
+	AverageScore         int                      `json:"average_score"`
+	SpecialCards         map[string]int           `json:"special_cards"`
+	Statistics           *statisticsDto           `json:"statistics"`
+	IsCardsRevealed      bool                     `json:"is_card_revealed"`
+	IsEveryoneVoted      bool                     `json:"is_everyone_voted"`
+	Cards                []cardDto                `json:"cards"`
+	Round                int                      `json:"round"`
+	Rounds               []roundDto               `json:"rounds"`
+	FinalEstimate        *string                  `json:"final_estimate"`
+	FinalEstimateHistory []finalEstimateChangeDto `json:"final_estimate_history"`
+}
+
+type finalEstimateChangeDto struct {
+	Value     *string   `json:"value"`
+	UserID    string    `json:"user_id"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type roundDto struct {
+	Round        int            `json:"round"`
+	MaxScore     float64        `json:"max_score"`
+	AverageScore int            `json:"average_score"`
+	SpecialCards map[string]int `json:"special_cards"`
+	Statistics   *statisticsDto `json:"statistics"`
+	Cards        []cardDto      `json:"cards"`
+}
+
+type statisticsDto struct {
+	Count             int               `json:"count"`
+	Min               float64           `json:"min"`
+	Max               float64           `json:"max"`
+	Average           float64           `json:"average"`
+	Median            float64           `json:"median"`
+	Mode              []string          `json:"mode"`
+	StandardDeviation float64           `json:"standard_deviation"`
+	Consensus         bool              `json:"consensus"`
+	NearestCard       string            `json:"nearest_card"`
+	Histogram         []histogramBarDto `json:"histogram"`
+}
+
+type histogramBarDto struct {
+	Card  string `json:"card"`
+	Count int    `json:"count"`
 }
 
 type cardDto struct {
-	Score  int       `json:"score"`
-	Player playerDto `json:"player"`
+	Card    string    `json:"card"`
+	Score   float64   `json:"score"`
+	Special string    `json:"special"`
+	Player  playerDto `json:"player"`
+}
+
+type deckDto struct {
+	Type         string   `json:"type"`
+	Cards        []string `json:"cards"`
+	SpecialCards []string `json:"special_cards"`
 }
 
 type gameResultDto struct {
-	GameID       string `json:"game_id"`
-	Name         string `json:"name"`
-	Status       string `json:"status"`
-	MaxScore     int    `json:"max_score"`
-	AverageScore int    `json:"average_score"`
+	GameID               string                   `json:"game_id"`
+	Name                 string                   `json:"name"`
+	Status               string                   `json:"status"`
+	MaxScore             float64                  `json:"max_score"`
+	AverageScore         int                      `json:"average_score"`
+	SpecialCards         map[string]int           `json:"special_cards"`
+	Statistics           *statisticsDto           `json:"statistics"`
+	Rounds               []roundDto               `json:"rounds"`
+	FinalEstimate        *string                  `json:"final_estimate"`
+	FinalEstimateHistory []finalEstimateChangeDto `json:"final_estimate_history"`
 }
 
 func NewRoomsController(authHelper *AuthHelper, roomsService *roomsdomain.RoomsService) *RoomsController {
@@ -77,7 +188,25 @@ func (rc *RoomsController) Post(c *gin.Context) {
 	request := roomsPostRequest{Name: "", InviteCodeRequired: false}
 	c.ShouldBindJSON(&request)
 
-	room, err := rc.roomsService.Create(user, request.Name, request.InviteCodeRequired)
+	deck := rooms.DefaultDeck()
+	if request.Deck != nil {
+		var err error
+		if deck, err = rooms.NewDeck(request.Deck.Type, request.Deck.Cards); err != nil {
+			handleRoomsError(c, err)
+			return
+		}
+	}
+
+	var autoReveal rooms.AutoReveal
+	if request.AutoReveal != nil {
+		var err error
+		if autoReveal, err = parseAutoReveal(*request.AutoReveal); err != nil {
+			handleRoomsError(c, err)
+			return
+		}
+	}
+
+	room, err := rc.roomsService.Create(user, request.Name, request.InviteCodeRequired, deck, autoReveal)
 	if err != nil {
 		handleRoomsError(c, err)
 		return
@@ -127,6 +256,103 @@ func (rc *RoomsController) Delete(c *gin.Context) {
 	c.AbortWithStatus(http.StatusOK)
 }
 
+// Leave removes the user from the room, the owner can't leave the room.
+func (rc *RoomsController) Leave(c *gin.Context) {
+	userID, ok := rc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	roomID, ok := requireRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := rc.roomsService.Leave(userID, roomID); err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// KickPlayer removes another player from the room on behalf of the owner or a moderator.
+func (rc *RoomsController) KickPlayer(c *gin.Context) {
+	userID, ok := rc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	roomID, ok := requireRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := rc.roomsService.Kick(userID, roomID, c.Param("user_id")); err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// PutOwner transfers the ownership of the room to another player.
+func (rc *RoomsController) PutOwner(c *gin.Context) {
+	userID, ok := rc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	roomID, ok := requireRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	var request ownerPutRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	room, err := rc.roomsService.TransferOwnership(userID, roomID, request.UserID)
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	response := roomDto{ID: room.ID, Name: room.Name, Owner: room.Owner}
+	c.JSON(http.StatusOK, response)
+}
+
+// PutPlayerRole changes the role of another player.
+func (rc *RoomsController) PutPlayerRole(c *gin.Context) {
+	userID, ok := rc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	roomID, ok := requireRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	var request playerRolePutRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	playerID := c.Param("user_id")
+	room, err := rc.roomsService.SetPlayerRole(userID, roomID, playerID, request.Role)
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	idx := slices.IndexFunc(room.Players, func(player rooms.Player) bool { return player.UserID == playerID })
+	c.JSON(http.StatusOK, mapPlayerToDto(room.Players[idx]))
+}
+
 func (rc *RoomsController) Join(c *gin.Context) {
 	roomID, ok := requireRoomIDParam(c)
 	if !ok {
@@ -136,9 +362,14 @@ func (rc *RoomsController) Join(c *gin.Context) {
 	if !ok {
 		return
 	}
-	inviteCode := c.Query("invite-code")
-
-	room, err := rc.roomsService.Join(user, roomID, inviteCode)
+	var room rooms.Room
+	var err error
+	role := c.Query("role")
+	if inviteLink := c.Query("invite-link"); len(inviteLink) > 0 {
+		room, err = rc.roomsService.JoinByLink(user, roomID, inviteLink, role)
+	} else {
+		room, err = rc.roomsService.Join(user, roomID, c.Query("invite-code"), role)
+	}
 	if err != nil {
 		handleRoomsError(c, err)
 		return
@@ -160,8 +391,12 @@ func (rc *RoomsController) GetState(c *gin.Context) {
 	}
 
 	commit := c.Query("commit")
+	wait, ok := parseWaitQuery(c)
+	if !ok {
+		return
+	}
 
-	roomState, err := rc.roomsService.GetState(userID, roomID)
+	roomState, err := rc.roomsService.WaitState(c.Request.Context(), userID, roomID, commit, wait)
 	if err != nil {
 		handleRoomsError(c, err)
 		return
@@ -169,36 +404,145 @@ func (rc *RoomsController) GetState(c *gin.Context) {
 
 	if roomState.Room.Commit == commit {
 		c.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapRoomStateToDto(roomState))
+}
+
+// GetLog returns the events of the room after the sequence number from the "after" query parameter.
+func (rc *RoomsController) GetLog(c *gin.Context) {
+	userID, ok := rc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
 	}
 
+	roomID, ok := requireRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	after := 0
+	if query := c.Query("after"); len(query) > 0 {
+		value, err := strconv.Atoi(query)
+		if err != nil || value < 0 {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		after = value
+	}
+
+	events, err := rc.roomsService.GetEvents(userID, roomID, after)
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	response := make([]roomEventDto, 0, len(events))
+	for _, event := range events {
+		dto := roomEventDto{
+			Sequence:  event.Sequence,
+			Type:      event.Type,
+			UserID:    event.UserID,
+			CreatedAt: event.CreatedAt,
+		}
+		if event.FinalEstimate != nil {
+			dto.FinalEstimate = &finalEstimateEventDto{GameID: event.GameID, Value: stringOrNil(event.FinalEstimate.Value)}
+		} else if event.Type == rooms.EventFinalEstimateChanged && len(event.Games) > 0 {
+			// events stored before the payloads were introduced carry the changed game
+			game := event.Games[0]
+			dto.FinalEstimate = &finalEstimateEventDto{GameID: game.ID, Value: stringOrNil(game.FinalEstimate)}
+		}
+		response = append(response, dto)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func requireRoomIDParam(c *gin.Context) (roomID string, ok bool) {
+	roomID = c.Param("room_id")
+	ok = true
+	if len(strings.TrimSpace(roomID)) == 0 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		ok = false
+	}
+	return
+}
+
+func parseWaitQuery(c *gin.Context) (time.Duration, bool) {
+	query := c.Query("wait")
+	if len(query) == 0 {
+		return 0, true
+	}
+	wait, err := time.ParseDuration(query)
+	if err != nil || wait < 0 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return 0, false
+	}
+	return min(wait, maxStateWait), true
+}
+
+func mapPlayerToDto(player rooms.Player) playerDto {
+	return playerDto{
+		ID:         player.UserID,
+		Name:       player.Name,
+		Color:      player.Color,
+		Avatar:     player.Avatar,
+		Role:       player.Role,
+		IsObserver: player.Role == rooms.RoleObserver,
+	}
+}
+
+func mapRoomStateToDto(roomState rooms.RoomState) roomStateDto {
+	roomDeck := roomState.Room.Deck
+	if len(roomDeck.Cards) == 0 {
+		roomDeck = rooms.DefaultDeck()
+	}
 	players := make([]playerDto, 0, len(roomState.Room.Players))
 	for _, player := range roomState.Room.Players {
-		players = append(players, mapPlayerToDto(player))
+		dto := mapPlayerToDto(player)
+		if len(roomState.Games) > 0 {
+			dto.HasVoted = rooms.HasVoted(roomState.Games[len(roomState.Games)-1], player.UserID)
+		}
+		players = append(players, dto)
 	}
 	var currentGame *currentGameDto = nil
 	if len(roomState.Games) > 0 {
 		game := roomState.Games[len(roomState.Games)-1]
-		maxScore := 0
+		maxScore := 0.0
 		averageScore := 0
 		isCardsRevealed := false
+		var statistics *statisticsDto = nil
 		cards := []cardDto{}
 		if game.Status == rooms.GameStatusCompleted {
 			maxScore = game.MaxScore
 			averageScore = game.AverageScore
 			isCardsRevealed = true
+			statistics = mapStatisticsToDto(game.Statistics)
 			cards = make([]cardDto, 0, len(game.Cards))
 			for _, card := range game.Cards {
-				cards = append(cards, cardDto{Score: card.Score, Player: mapPlayerToDto(card.Player)})
+				cards = append(cards, mapCardToDto(card))
 			}
 		}
 		currentGame = &currentGameDto{
-			ID:              game.ID,
-			Name:            game.Name,
-			Status:          game.Status,
-			MaxScore:        maxScore,
-			AverageScore:    averageScore,
-			IsCardsRevealed: isCardsRevealed,
-			Cards:           cards,
+			ID:                   game.ID,
+			Name:                 game.Name,
+			Status:               game.Status,
+			Deck:                 mapDeckToDto(game.Deck),
+			AutoReveal:           mapAutoRevealToDto(game.AutoReveal),
+			RevealAt:             timeOrNil(game.RevealAt),
+			Deadline:             timeOrNil(game.Deadline),
+			ServerTime:           time.Now(),
+			MaxScore:             maxScore,
+			AverageScore:         averageScore,
+			SpecialCards:         mapSpecialCardCountsToDto(game.SpecialCardCounts),
+			Statistics:           statistics,
+			IsCardsRevealed:      isCardsRevealed,
+			IsEveryoneVoted:      rooms.IsEveryoneVoted(roomState.Room, game),
+			Cards:                cards,
+			Round:                len(game.Rounds) + 1,
+			Rounds:               mapRoundsToDto(game.Rounds),
+			FinalEstimate:        stringOrNil(game.FinalEstimate),
+			FinalEstimateHistory: mapFinalEstimateChangesToDto(game.FinalEstimateChanges),
 		}
 	}
 	var results []gameResultDto
@@ -206,42 +550,134 @@ func (rc *RoomsController) GetState(c *gin.Context) {
 		results = make([]gameResultDto, 0, len(roomState.Games)-1)
 		for _, game := range roomState.Games {
 			results = append(results, gameResultDto{
-				GameID:       game.ID,
-				Name:         game.Name,
-				Status:       game.Status,
-				MaxScore:     game.MaxScore,
-				AverageScore: game.AverageScore,
+				GameID:               game.ID,
+				Name:                 game.Name,
+				Status:               game.Status,
+				MaxScore:             game.MaxScore,
+				AverageScore:         game.AverageScore,
+				SpecialCards:         mapSpecialCardCountsToDto(game.SpecialCardCounts),
+				Statistics:           mapStatisticsToDto(game.Statistics),
+				Rounds:               mapRoundsToDto(game.Rounds),
+				FinalEstimate:        stringOrNil(game.FinalEstimate),
+				FinalEstimateHistory: mapFinalEstimateChangesToDto(game.FinalEstimateChanges),
 			})
 		}
 	} else {
 		results = []gameResultDto{}
 	}
-	response := roomStateDto{
+	return roomStateDto{
 		RoomID:      roomState.Room.ID,
 		Name:        roomState.Room.Name,
 		Owner:       roomState.Room.Owner,
 		Commit:      roomState.Room.Commit,
+		Sequence:    roomState.Room.Sequence,
+		Deck:        *mapDeckToDto(roomDeck),
+		AutoReveal:  mapAutoRevealToDto(roomState.Room.AutoReveal),
 		Players:     players,
 		CurrentGame: currentGame,
 		GameResults: results,
 	}
-	c.JSON(http.StatusOK, response)
 }
 
-func requireRoomIDParam(c *gin.Context) (roomID string, ok bool) {
-	roomID = c.Param("room_id")
-	ok = true
-	if len(strings.TrimSpace(roomID)) == 0 {
-		c.AbortWithStatus(http.StatusBadRequest)
-		ok = false
+func mapCardToDto(card rooms.Card) cardDto {
+	value := card.Value
+	if len(value) == 0 {
+		value = rooms.FormatScore(card.Score)
 	}
-	return
+	return cardDto{Card: value, Score: card.Score, Special: card.Special, Player: mapPlayerToDto(card.Player)}
 }
 
-func mapPlayerToDto(player rooms.Player) playerDto {
-	return playerDto{
-		ID:    player.UserID,
-		Name:  player.Name,
-		Color: player.Color,
+// mapSpecialCardCountsToDto counts every type of special cards, the counts are zero until the game is completed.
+func mapSpecialCardCountsToDto(specialCardCounts map[string]int) map[string]int {
+	counts := make(map[string]int, len(rooms.SpecialCards))
+	for _, special := range rooms.SpecialCards {
+		counts[special] = specialCardCounts[special]
+	}
+	return counts
+}
+
+// mapDeckToDto returns nil for games created before decks were introduced.
+func mapDeckToDto(deck rooms.Deck) *deckDto {
+	if len(deck.Cards) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(deck.Cards))
+	for _, card := range deck.Cards {
+		values = append(values, card.Value)
+	}
+	specialValues := make([]string, 0, len(rooms.SpecialCards))
+	for _, special := range rooms.SpecialCards {
+		specialValues = append(specialValues, rooms.SpecialCardValue(special))
+	}
+	return &deckDto{Type: deck.Type, Cards: values, SpecialCards: specialValues}
+}
+
+func mapAutoRevealToDto(autoReveal rooms.AutoReveal) autoRevealDto {
+	return autoRevealDto{Enabled: autoReveal.Enabled, Delay: autoReveal.Delay.String()}
+}
+
+func parseAutoReveal(request autoRevealRequest) (rooms.AutoReveal, error) {
+	delay := time.Duration(0)
+	if len(request.Delay) > 0 {
+		value, err := time.ParseDuration(request.Delay)
+		if err != nil {
+			return rooms.AutoReveal{}, rooms.ErrInvalidAutoReveal
+		}
+		delay = value
+	}
+	return rooms.NewAutoReveal(request.Enabled, delay)
+}
+
+// mapRoundsToDto maps the previous rounds of the game, they are always revealed.
+func mapRoundsToDto(rounds []rooms.Round) []roundDto {
+	result := make([]roundDto, 0, len(rounds))
+	for i, round := range rounds {
+		cards := make([]cardDto, 0, len(round.Cards))
+		for _, card := range round.Cards {
+			cards = append(cards, mapCardToDto(card))
+		}
+		result = append(result, roundDto{
+			Round:        i + 1,
+			MaxScore:     round.MaxScore,
+			AverageScore: round.AverageScore,
+			SpecialCards: mapSpecialCardCountsToDto(round.SpecialCardCounts),
+			Statistics:   mapStatisticsToDto(round.Statistics),
+			Cards:        cards,
+		})
+	}
+	return result
+}
+
+func mapFinalEstimateChangesToDto(changes []rooms.FinalEstimateChange) []finalEstimateChangeDto {
+	result := make([]finalEstimateChangeDto, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, finalEstimateChangeDto{
+			Value:     stringOrNil(change.Value),
+			UserID:    change.UserID,
+			ChangedAt: change.ChangedAt,
+		})
+	}
+	return result
+}
+
+func mapStatisticsToDto(statistics *rooms.Statistics) *statisticsDto {
+	if statistics == nil {
+		return nil
+	}
+	histogram := make([]histogramBarDto, 0, len(statistics.Histogram))
+	for _, bar := range statistics.Histogram {
+		histogram = append(histogram, histogramBarDto{Card: bar.Value, Count: bar.Count})
+	}
+	return &statisticsDto{
+		Count:             statistics.Count,
+		Min:               statistics.Min,
+		Max:               statistics.Max,
+		Average:           statistics.Average,
+		Median:            statistics.Median,
+		Mode:              emptyIfNil(statistics.Modes),
+		StandardDeviation: statistics.StandardDeviation,
+		Consensus:         statistics.Consensus,
+		NearestCard:       statistics.NearestCard,
+		Histogram:         histogram,
 	}
 }
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	socketMessageState = "state"
+	socketMessageDelta = "delta"
+	socketWriteTimeout = 10 * time.Second
+
+	eventState        = "state"
+	eventKeepAlive    = ": keep-alive\n\n"
+	eventKeepAliveGap = 25 * time.Second
+)
+
+type socketMessageDto struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// roomStateDeltaDto has the commit, the sequence and the changed fields of the room state keyed by their JSON names.
+// A changed field is always present, so a current game which ended is sent as null and emptied lists as [].
+type roomStateDeltaDto map[string]any
+
+// Subscribe upgrades the connection to a WebSocket which receives the full room state first
+// and then a delta with the changed fields each time the room commit changes.
+func (rc *RoomsController) Subscribe(c *gin.Context) {
+	userID, ok := rc.authHelper.ResolveStreamUserID(c)
+	if !ok {
+		return
+	}
+
+	roomID, ok := requireRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	roomState, changed, err := rc.roomsService.ObserveState(userID, roomID)
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	server := websocket.Server{
+		Handshake: acceptSocketHandshake,
+		Handler: func(conn *websocket.Conn) {
+			rc.pushRoomState(conn, userID, roomID, roomState, changed)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+func (rc *RoomsController) pushRoomState(conn *websocket.Conn, userID string, roomID string, roomState rooms.RoomState, changed <-chan struct{}) {
+	defer conn.Close()
+
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		var message []byte
+		for websocket.Message.Receive(conn, &message) == nil {
+			rc.roomsService.KeepAlive(userID, roomID)
+		}
+	}()
+
+	state := mapRoomStateToDto(roomState)
+	if !sendSocketMessage(conn, socketMessageDto{Type: socketMessageState, Data: state}) {
+		return
+	}
+
+	for {
+		select {
+		case <-disconnected:
+			return
+		case <-changed:
+		}
+
+		var err error
+		roomState, changed, err = rc.roomsService.ObserveState(userID, roomID)
+		if err != nil {
+			return
+		}
+
+		next := mapRoomStateToDto(roomState)
+		if next.Commit == state.Commit {
+			continue
+		}
+		delta := diffRoomState(state, next)
+		if !sendSocketMessage(conn, socketMessageDto{Type: socketMessageDelta, Data: delta}) {
+			return
+		}
+		state = next
+	}
+}
+
+// Events streams the room state as Server-Sent Events, a "state" event is emitted on every change
+// of the room commit. The event ID is the room sequence, so a client reconnecting with an older
+// Last-Event-ID header receives the current state at once.
+func (rc *RoomsController) Events(c *gin.Context) {
+	userID, ok := rc.authHelper.ResolveStreamUserID(c)
+	if !ok {
+		return
+	}
+
+	roomID, ok := requireRoomIDParam(c)
+	if !ok {
+		return
+	}
+
+	roomState, changed, err := rc.roomsService.ObserveState(userID, roomID)
+	if err != nil {
+		handleRoomsError(c, err)
+		return
+	}
+
+	lastSequence, err := strconv.Atoi(c.GetHeader("Last-Event-ID"))
+	if err != nil {
+		lastSequence = -1
+	}
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		if roomState.Room.Sequence != lastSequence {
+			lastSequence = roomState.Room.Sequence
+			c.Render(-1, sse.Event{
+				Id:    strconv.Itoa(lastSequence),
+				Event: eventState,
+				Data:  mapRoomStateToDto(roomState),
+			})
+			return true
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-time.After(eventKeepAliveGap):
+			io.WriteString(w, eventKeepAlive)
+			rc.roomsService.KeepAlive(userID, roomID)
+			return true
+		case <-changed:
+		}
+
+		roomState, changed, err = rc.roomsService.ObserveState(userID, roomID)
+		return err == nil
+	})
+}
+
+func sendSocketMessage(conn *websocket.Conn, message socketMessageDto) bool {
+	conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+	if err := websocket.JSON.Send(conn, message); err != nil {
+		log.Printf("room state socket closed: %v", err)
+		return false
+	}
+	return true
+}
+
+// acceptSocketHandshake allows connections from any origin, clients are authorized by the access token
+// given explicitly in the header or the query, so another site can't open a socket with the credentials of a user.
+func acceptSocketHandshake(config *websocket.Config, request *http.Request) error {
+	return nil
+}
+
+func diffRoomState(prev roomStateDto, next roomStateDto) roomStateDeltaDto {
+	delta := roomStateDeltaDto{"commit": next.Commit, "sequence": next.Sequence}
+	if prev.Name != next.Name {
+		delta["name"] = next.Name
+	}
+	if prev.Owner != next.Owner {
+		delta["owner"] = next.Owner
+	}
+	if !reflect.DeepEqual(prev.Deck, next.Deck) {
+		delta["deck"] = next.Deck
+	}
+	if prev.AutoReveal != next.AutoReveal {
+		delta["auto_reveal"] = next.AutoReveal
+	}
+	if !reflect.DeepEqual(prev.Players, next.Players) {
+		delta["players"] = emptyIfNil(next.Players)
+	}
+	if !isCurrentGameEqual(prev.CurrentGame, next.CurrentGame) {
+		delta["current_game"] = next.CurrentGame
+	}
+	if !reflect.DeepEqual(prev.GameResults, next.GameResults) {
+		delta["game_results"] = emptyIfNil(next.GameResults)
+	}
+	return delta
+}
+
+// isCurrentGameEqual compares the current games ignoring the server time, it changes on every mapping.
+func isCurrentGameEqual(prev *currentGameDto, next *currentGameDto) bool {
+	if prev == nil || next == nil {
+		return prev == next
+	}
+	prevGame := *prev
+	prevGame.ServerTime = next.ServerTime
+	return reflect.DeepEqual(prevGame, *next)
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDiffRoomState(t *testing.T) {
+	prev := roomStateDto{
+		Name:        "Room",
+		Owner:       "owner",
+		Commit:      "1",
+		Sequence:    1,
+		Players:     []playerDto{{ID: "owner"}},
+		CurrentGame: &currentGameDto{ID: "game", ServerTime: time.Now()},
+		GameResults: []gameResultDto{{GameID: "game"}},
+	}
+	tests := []struct {
+		name string
+		next roomStateDto
+		want string
+	}{
+		{
+			name: "unchanged fields are omitted",
+			next: roomStateDto{
+				Name:        "Room",
+				Owner:       "owner",
+				Commit:      "2",
+				Sequence:    2,
+				Players:     []playerDto{{ID: "owner"}},
+				CurrentGame: &currentGameDto{ID: "game", ServerTime: time.Now().Add(time.Second)},
+				GameResults: []gameResultDto{{GameID: "game"}},
+			},
+			want: `{"commit":"2","sequence":2}`,
+		},
+		{
+			name: "ended game and emptied lists are present",
+			next: roomStateDto{
+				Name:        "Room",
+				Owner:       "owner",
+				Commit:      "2",
+				Sequence:    2,
+				GameResults: []gameResultDto{},
+			},
+			want: `{"commit":"2","current_game":null,"game_results":[],"players":[],"sequence":2}`,
+		},
+		{
+			name: "changed name",
+			next: roomStateDto{
+				Name:        "Renamed",
+				Owner:       "owner",
+				Commit:      "2",
+				Sequence:    2,
+				Players:     []playerDto{{ID: "owner"}},
+				CurrentGame: prev.CurrentGame,
+				GameResults: []gameResultDto{{GameID: "game"}},
+			},
+			want: `{"commit":"2","name":"Renamed","sequence":2}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(diffRoomState(prev, test.next))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.want {
+				t.Errorf("diffRoomState() = %s, want %s", data, test.want)
+			}
+		})
+	}
+}
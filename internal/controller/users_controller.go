@@ -1,15 +1,20 @@
 package controller
 
 import (
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"aleksandersh.github.io/planning-poker-server/internal/users"
 	"aleksandersh.github.io/planning-poker-server/internal/users/usersdomain"
 	"github.com/gin-gonic/gin"
 )
 
 type UsersController struct {
-	service *usersdomain.Service
+	authHelper *AuthHelper
+	service    *usersdomain.Service
 }
 
 type usersRegisterRequest struct {
@@ -17,17 +22,48 @@ type usersRegisterRequest struct {
 }
 
 type usersRegisterResponse struct {
-	User        userDto `json:"user"`
-	AccessToken string  `json:"access_token"`
+	User        userDto   `json:"user"`
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
 }
 
 type userDto struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Color  string `json:"color"`
+	Avatar string `json:"avatar"`
+}
+
+type userPatchRequest struct {
+	Name   *string `json:"name"`
+	Color  *string `json:"color"`
+	Avatar *string `json:"avatar"`
 }
 
-func NewUsersController(service *usersdomain.Service) *UsersController {
-	return &UsersController{service: service}
+type deletionReportDto struct {
+	UserID           string   `json:"user_id"`
+	RevokedSessions  int      `json:"revoked_sessions"`
+	UnlinkedIdentity bool     `json:"unlinked_identity"`
+	LeftRooms        []string `json:"left_rooms"`
+	TransferredRooms []string `json:"transferred_rooms"`
+	DeletedRooms     []string `json:"deleted_rooms"`
+	AnonymizedCards  int      `json:"anonymized_cards"`
+}
+
+type tokenDto struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type sessionDto struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+}
+
+func NewUsersController(authHelper *AuthHelper, service *usersdomain.Service) *UsersController {
+	return &UsersController{authHelper: authHelper, service: service}
 }
 
 func (uc *UsersController) Register(c *gin.Context) {
@@ -39,9 +75,172 @@ func (uc *UsersController) Register(c *gin.Context) {
 		return
 	}
 
-	user, accessToken := uc.service.Add(request.Name)
+	user, session, err := uc.service.Add(request.Name)
+	if err != nil {
+		log.Println(fmt.Errorf("registration failed: %w", err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
 
-	userDto := userDto{ID: user.ID, Name: user.Name}
-	response := usersRegisterResponse{User: userDto, AccessToken: accessToken}
+	response := usersRegisterResponse{User: mapUserToDto(user), AccessToken: session.AccessToken, ExpiresAt: session.ExpiresAt}
 	c.JSON(http.StatusCreated, response)
 }
+
+// RefreshToken replaces the access token of the request with a new one, the old token is revoked.
+func (uc *UsersController) RefreshToken(c *gin.Context) {
+	session, ok := uc.authHelper.ResolveSession(c)
+	if !ok {
+		return
+	}
+
+	session, err := uc.service.RefreshToken(session.AccessToken)
+	if err != nil {
+		handleUsersError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenDto{AccessToken: session.AccessToken, ExpiresAt: session.ExpiresAt})
+}
+
+// Logout revokes the access token of the request.
+func (uc *UsersController) Logout(c *gin.Context) {
+	session, ok := uc.authHelper.ResolveSession(c)
+	if !ok {
+		return
+	}
+
+	if err := uc.service.RevokeSession(session.UserID, session.ID); err != nil {
+		handleUsersError(c, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+func (uc *UsersController) GetSessions(c *gin.Context) {
+	current, ok := uc.authHelper.ResolveSession(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := uc.service.GetSessions(current.UserID)
+	if err != nil {
+		handleUsersError(c, err)
+		return
+	}
+
+	response := make([]sessionDto, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, mapSessionToDto(session, current.ID))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteSession revokes a session of the user, including the current one.
+func (uc *UsersController) DeleteSession(c *gin.Context) {
+	userID, ok := uc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := uc.service.RevokeSession(userID, c.Param("session_id")); err != nil {
+		handleUsersError(c, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// DeleteSessions revokes all sessions of the user, including the current one.
+func (uc *UsersController) DeleteSessions(c *gin.Context) {
+	userID, ok := uc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := uc.service.RevokeSessions(userID); err != nil {
+		handleUsersError(c, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+func (uc *UsersController) GetMe(c *gin.Context) {
+	userID, ok := uc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := uc.service.GetProfile(userID)
+	if err != nil {
+		handleUsersError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapUserToDto(user))
+}
+
+// PatchMe changes the fields of the profile present in the request, the changes are shown in every room of the user.
+func (uc *UsersController) PatchMe(c *gin.Context) {
+	userID, ok := uc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	var request userPatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	update := usersdomain.ProfileUpdate{Name: request.Name, Color: request.Color, Avatar: request.Avatar}
+	user, err := uc.service.UpdateProfile(userID, update)
+	if err != nil {
+		handleUsersError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapUserToDto(user))
+}
+
+// DeleteMe erases the user everywhere and reports what was removed.
+func (uc *UsersController) DeleteMe(c *gin.Context) {
+	userID, ok := uc.authHelper.ResolveUserID(c)
+	if !ok {
+		return
+	}
+
+	report, err := uc.service.Delete(userID)
+	if err != nil {
+		handleUsersError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapDeletionReportToDto(report))
+}
+
+func mapDeletionReportToDto(report usersdomain.DeletionReport) deletionReportDto {
+	return deletionReportDto{
+		UserID:           report.UserID,
+		RevokedSessions:  report.RevokedSessions,
+		UnlinkedIdentity: report.UnlinkedIdentity,
+		LeftRooms:        emptyIfNil(report.Rooms.LeftRooms),
+		TransferredRooms: emptyIfNil(report.Rooms.TransferredRooms),
+		DeletedRooms:     emptyIfNil(report.Rooms.DeletedRooms),
+		AnonymizedCards:  report.Rooms.AnonymizedCards,
+	}
+}
+
+func mapUserToDto(user users.User) userDto {
+	return userDto{ID: user.ID, Name: user.Name, Color: user.Color, Avatar: user.Avatar}
+}
+
+func mapSessionToDto(session users.Session, currentSessionID string) sessionDto {
+	return sessionDto{
+		ID:        session.ID,
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+		Current:   session.ID == currentSessionID,
+	}
+}
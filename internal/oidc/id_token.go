@@ -0,0 +1,196 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// clockSkew is tolerated between the server and the provider when the expiration is checked.
+const clockSkew = time.Minute
+
+type idTokenHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type idTokenClaims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	ExpiresAt         int64    `json:"exp"`
+	Nonce             string   `json:"nonce"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+// audience is a single string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Curve   string `json:"crv"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// verifyIDToken checks the signature, issuer, audience, expiration and nonce of the ID token.
+// RS256 and ES256 signatures are supported.
+func (p *Provider) verifyIDToken(ctx context.Context, idToken string, nonce string) (Claims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	var header idTokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, ErrInvalidIDToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrInvalidIDToken
+	}
+	key, err := p.getKey(ctx, header.KeyID)
+	if err != nil {
+		return Claims{}, err
+	}
+	if !verifySignature(header.Algorithm, key, parts[0]+"."+parts[1], signature) {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	var claims idTokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, ErrInvalidIDToken
+	}
+	if claims.Issuer != p.config.Issuer || !slices.Contains(claims.Audience, p.config.ClientID) {
+		return Claims{}, ErrInvalidIDToken
+	}
+	if !time.Now().Add(-clockSkew).Before(time.Unix(claims.ExpiresAt, 0)) {
+		return Claims{}, ErrInvalidIDToken
+	}
+	if claims.Subject == "" || claims.Nonce != nonce {
+		return Claims{}, ErrInvalidIDToken
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	return Claims{Issuer: claims.Issuer, Subject: claims.Subject, Name: name}, nil
+}
+
+// getKey returns the key of the provider by its ID, the keys are fetched again if the key is unknown,
+// since the provider may have rotated them.
+func (p *Provider) getKey(ctx context.Context, keyID string) (any, error) {
+	p.mutex.Lock()
+	key, contains := p.keys[keyID]
+	p.mutex.Unlock()
+	if contains {
+		return key, nil
+	}
+
+	m, err := p.getMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, m.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set jwks
+	if err := p.doJSON(request, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch provider keys: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if parsed := k.parse(); parsed != nil {
+			keys[k.KeyID] = parsed
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.keys = keys
+	key, contains = p.keys[keyID]
+	if !contains {
+		return nil, ErrInvalidIDToken
+	}
+	return key, nil
+}
+
+// parse returns a public key of the JWK, or nil if the key type isn't supported.
+func (k jwk) parse() any {
+	switch k.KeyType {
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			return nil
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil || k.Curve != "P-256" {
+			return nil
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	default:
+		return nil
+	}
+}
+
+func verifySignature(algorithm string, key any, signed string, signature []byte) bool {
+	hash := sha256.Sum256([]byte(signed))
+	switch algorithm {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, hash[:], signature) == nil
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(ecKey, hash[:], r, s)
+	default:
+		return false
+	}
+}
+
+func decodeSegment(encoded string, value any) error {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
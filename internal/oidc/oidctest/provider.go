@@ -0,0 +1,181 @@
+// Package oidctest provides a local stand-in OpenID Connect provider for tests.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/utils/idutils"
+)
+
+const (
+	ClientID     = "poker-client"
+	ClientSecret = "poker-secret"
+
+	keyID = "test-key"
+)
+
+// Provider serves the discovery document, the keys and the authorization and token endpoints.
+// The authorization endpoint logs in the subject given by the "login_hint" parameter without a login page.
+type Provider struct {
+	Server *httptest.Server
+
+	// ModifyClaims changes claims of issued ID tokens, it lets tests issue invalid tokens.
+	ModifyClaims func(claims map[string]any)
+	// ForgeSignature signs issued ID tokens by a key which isn't published by the provider.
+	ForgeSignature bool
+
+	key       *rsa.PrivateKey
+	forgedKey *rsa.PrivateKey
+
+	mutex  sync.Mutex
+	logins map[string]login
+}
+
+type login struct {
+	Subject string
+	Name    string
+	Nonce   string
+}
+
+func NewProvider() *Provider {
+	p := &Provider{key: generateKey(), forgedKey: generateKey(), logins: make(map[string]login)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("GET /jwks", p.handleKeys)
+	mux.HandleFunc("GET /authorize", p.handleAuthorize)
+	mux.HandleFunc("POST /token", p.handleToken)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// Issuer returns the URL of the provider.
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+func (p *Provider) Close() {
+	p.Server.Close()
+}
+
+// Login returns an authorization code for the subject as if the user logged in at the provider.
+func (p *Provider) Login(subject string, name string, nonce string) string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	code := idutils.GenerateID()
+	p.logins[code] = login{Subject: subject, Name: name, Nonce: nonce}
+	return code
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{
+		"issuer":                 p.Issuer(),
+		"authorization_endpoint": p.Issuer() + "/authorize",
+		"token_endpoint":         p.Issuer() + "/token",
+		"jwks_uri":               p.Issuer() + "/jwks",
+	})
+}
+
+func (p *Provider) handleKeys(w http.ResponseWriter, r *http.Request) {
+	key := p.key.PublicKey
+	writeJSON(w, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != ClientID || query.Get("redirect_uri") == "" {
+		http.Error(w, "invalid client", http.StatusBadRequest)
+		return
+	}
+	subject := query.Get("login_hint")
+	if subject == "" {
+		subject = "subject"
+	}
+	code := p.Login(subject, "User "+subject, query.Get("nonce"))
+
+	redirect := query.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != ClientID || clientSecret != ClientSecret {
+		http.Error(w, "invalid client", http.StatusUnauthorized)
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		http.Error(w, "unsupported grant type", http.StatusBadRequest)
+		return
+	}
+
+	p.mutex.Lock()
+	l, contains := p.logins[r.PostFormValue("code")]
+	delete(p.logins, r.PostFormValue("code"))
+	p.mutex.Unlock()
+	if !contains {
+		http.Error(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+
+	claims := map[string]any{
+		"iss":   p.Issuer(),
+		"sub":   l.Subject,
+		"aud":   ClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": l.Nonce,
+		"name":  l.Name,
+	}
+	if p.ModifyClaims != nil {
+		p.ModifyClaims(claims)
+	}
+	key := p.key
+	if p.ForgeSignature {
+		key = p.forgedKey
+	}
+	writeJSON(w, map[string]string{"id_token": signIDToken(key, claims), "token_type": "Bearer"})
+}
+
+func signIDToken(key *rsa.PrivateKey, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": keyID, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func generateKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,157 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const requestTimeout = 10 * time.Second
+
+var (
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+type Config struct {
+	// Issuer is a URL of the provider, its metadata is discovered at "<issuer>/.well-known/openid-configuration".
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL receives the authorization code, it must be registered at the provider.
+	RedirectURL string
+}
+
+// Claims are claims of a verified ID token which identify the user.
+type Claims struct {
+	Issuer  string
+	Subject string
+	Name    string
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	IDToken string `json:"id_token"`
+}
+
+// Provider runs the authorization code flow of an OpenID Connect provider. The metadata and keys of the provider
+// are fetched on first use and the keys are fetched again when a token is signed by an unknown key.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mutex    sync.Mutex
+	metadata *metadata
+	keys     map[string]any
+}
+
+func NewProvider(config Config) *Provider {
+	return &Provider{config: config, client: &http.Client{Timeout: requestTimeout}}
+}
+
+func (p *Provider) Issuer() string {
+	return p.config.Issuer
+}
+
+// AuthCodeURL returns a URL of the provider login page, the provider redirects back with the state
+// and the nonce is expected in the ID token.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string) (string, error) {
+	m, err := p.getMetadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.config.ClientID},
+		"redirect_uri":  {p.config.RedirectURL},
+		"scope":         {"openid profile"},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	separator := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return m.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems the authorization code for an ID token and returns its claims once it is verified.
+func (p *Provider) Exchange(ctx context.Context, code string, nonce string) (Claims, error) {
+	m, err := p.getMetadata(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {p.config.RedirectURL},
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Claims{}, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	var response tokenResponse
+	if err := p.doJSON(request, &response); err != nil {
+		return Claims{}, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	return p.verifyIDToken(ctx, response.IDToken, nonce)
+}
+
+func (p *Provider) getMetadata(ctx context.Context) (*metadata, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	discoveryURL := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	var m metadata
+	if err := p.doJSON(request, &m); err != nil {
+		return nil, fmt.Errorf("failed to discover provider: %w", err)
+	}
+	if m.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("provider issuer %q doesn't match %q", m.Issuer, p.config.Issuer)
+	}
+	p.metadata = &m
+	return p.metadata, nil
+}
+
+func (p *Provider) doJSON(request *http.Request, result any) error {
+	request.Header.Set("Accept", "application/json")
+	response, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d: %s", response.StatusCode, body)
+	}
+	return json.Unmarshal(body, result)
+}
@@ -0,0 +1,111 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/oidc/oidctest"
+)
+
+func newTestProvider(issuer string) *Provider {
+	return NewProvider(Config{
+		Issuer:       issuer,
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "http://localhost/callback",
+	})
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	fake := oidctest.NewProvider()
+	defer fake.Close()
+	provider := newTestProvider(fake.Issuer())
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if got := parsed.Scheme + "://" + parsed.Host + parsed.Path; got != fake.Issuer()+"/authorize" {
+		t.Errorf("endpoint = %q, want %q", got, fake.Issuer()+"/authorize")
+	}
+	for key, want := range map[string]string{"client_id": oidctest.ClientID, "state": "state", "nonce": "nonce", "response_type": "code"} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	fake := oidctest.NewProvider()
+	defer fake.Close()
+	provider := newTestProvider(fake.Issuer())
+
+	code := fake.Login("alice-sub", "Alice", "nonce")
+	claims, err := provider.Exchange(context.Background(), code, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Claims{Issuer: fake.Issuer(), Subject: "alice-sub", Name: "Alice"}
+	if claims != want {
+		t.Errorf("claims = %+v, want %+v", claims, want)
+	}
+
+	if _, err := provider.Exchange(context.Background(), code, "nonce"); err == nil {
+		t.Error("Exchange() with a redeemed code succeeded")
+	}
+}
+
+func TestExchangeRejectsInvalidIDToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		nonce          string
+		modifyClaims   func(claims map[string]any)
+		forgeSignature bool
+	}{
+		{name: "forged signature", forgeSignature: true},
+		{name: "other nonce", nonce: "other"},
+		{name: "other audience", modifyClaims: func(claims map[string]any) { claims["aud"] = "other-client" }},
+		{name: "audience list without the client", modifyClaims: func(claims map[string]any) { claims["aud"] = []string{"a", "b"} }},
+		{name: "other issuer", modifyClaims: func(claims map[string]any) { claims["iss"] = "https://other.example.com" }},
+		{name: "expired", modifyClaims: func(claims map[string]any) { claims["exp"] = time.Now().Add(-2 * clockSkew).Unix() }},
+		{name: "no subject", modifyClaims: func(claims map[string]any) { delete(claims, "sub") }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := oidctest.NewProvider()
+			defer fake.Close()
+			fake.ModifyClaims = test.modifyClaims
+			fake.ForgeSignature = test.forgeSignature
+			provider := newTestProvider(fake.Issuer())
+
+			nonce := test.nonce
+			if nonce == "" {
+				nonce = "nonce"
+			}
+			code := fake.Login("alice-sub", "Alice", "nonce")
+			if _, err := provider.Exchange(context.Background(), code, nonce); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Exchange() error = %v, want %v", err, ErrInvalidIDToken)
+			}
+		})
+	}
+}
+
+func TestExchangeAcceptsAudienceList(t *testing.T) {
+	fake := oidctest.NewProvider()
+	defer fake.Close()
+	fake.ModifyClaims = func(claims map[string]any) { claims["aud"] = []string{"other-client", oidctest.ClientID} }
+	provider := newTestProvider(fake.Issuer())
+
+	code := fake.Login("alice-sub", "Alice", "nonce")
+	if _, err := provider.Exchange(context.Background(), code, "nonce"); err != nil {
+		t.Errorf("Exchange() error = %v", err)
+	}
+}
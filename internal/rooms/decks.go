@@ -0,0 +1,161 @@
+package rooms
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	DeckFibonacci         = "fibonacci"
+	DeckModifiedFibonacci = "modified_fibonacci"
+	DeckPowersOfTwo       = "powers_of_two"
+	DeckTShirt            = "tshirt"
+	DeckCustom            = "custom"
+
+	customDeckMaxCards     = 20
+	customDeckMaxCardValue = 8
+)
+
+var (
+	ErrInvalidDeck = errors.New("invalid deck")
+	ErrInvalidCard = errors.New("card is not in the deck")
+)
+
+// Deck is a set of cards which players choose from.
+type Deck struct {
+	Type  string
+	Cards []DeckCard
+}
+
+// DeckCard is shown to players by its value, the score of the card is used to estimate games.
+type DeckCard struct {
+	Value string
+	Score float64
+	// Special is a type of the special card, special cards aren't part of decks.
+	Special string
+}
+
+var specialCardValues = map[string]string{
+	"?": SpecialCardUnsure,
+	"☕": SpecialCardBreak,
+	"∞": SpecialCardInfinity,
+}
+
+var deckCards = map[string][]DeckCard{
+	DeckFibonacci:         numericCards(0, 1, 2, 3, 5, 8, 13, 21, 34, 55, 89),
+	DeckModifiedFibonacci: numericCards(0, 0.5, 1, 2, 3, 5, 8, 13, 20, 40, 100),
+	DeckPowersOfTwo:       numericCards(0, 1, 2, 4, 8, 16, 32, 64),
+	DeckTShirt: {
+		{Value: "XS", Score: 1},
+		{Value: "S", Score: 2},
+		{Value: "M", Score: 3},
+		{Value: "L", Score: 5},
+		{Value: "XL", Score: 8},
+		{Value: "XXL", Score: 13},
+	},
+}
+
+// DefaultDeck is used by rooms created without a deck.
+func DefaultDeck() Deck {
+	return Deck{Type: DeckFibonacci, Cards: deckCards[DeckFibonacci]}
+}
+
+// NewDeck returns the deck of the type, the card values are required only for the custom deck.
+// Cards of a custom deck are scored by their values if all of them are numbers or by their positions otherwise.
+func NewDeck(deckType string, values []string) (Deck, error) {
+	if deckType != DeckCustom {
+		cards, contains := deckCards[deckType]
+		if !contains || len(values) > 0 {
+			return Deck{}, ErrInvalidDeck
+		}
+		return Deck{Type: deckType, Cards: cards}, nil
+	}
+
+	if len(values) == 0 || len(values) > customDeckMaxCards {
+		return Deck{}, ErrInvalidDeck
+	}
+	cards := make([]DeckCard, 0, len(values))
+	numeric := 0
+	for i, value := range values {
+		if len(value) == 0 || utf8.RuneCountInString(value) > customDeckMaxCardValue || slices.Index(values, value) != i {
+			return Deck{}, ErrInvalidDeck
+		}
+		if _, special := specialCardValues[value]; special {
+			return Deck{}, ErrInvalidDeck
+		}
+		score, ok := parseScore(value)
+		if ok {
+			numeric++
+		} else {
+			score = float64(i + 1)
+		}
+		cards = append(cards, DeckCard{Value: value, Score: score})
+	}
+	if numeric > 0 && numeric < len(values) {
+		return Deck{}, ErrInvalidDeck
+	}
+	return Deck{Type: DeckCustom, Cards: cards}, nil
+}
+
+// FindCard returns the card of the deck or a special card by its value. Games created before decks
+// were introduced have no deck and accept any number.
+func FindCard(deck Deck, value string) (DeckCard, bool) {
+	if special, contains := specialCardValues[value]; contains {
+		return DeckCard{Value: value, Special: special}, true
+	}
+	if len(deck.Cards) == 0 {
+		score, ok := parseScore(value)
+		return DeckCard{Value: value, Score: score}, ok
+	}
+	idx := slices.IndexFunc(deck.Cards, func(card DeckCard) bool { return card.Value == value })
+	if idx == UnknownIndex {
+		return DeckCard{}, false
+	}
+	return deck.Cards[idx], true
+}
+
+// SpecialCardValue returns the value of the special card type.
+func SpecialCardValue(special string) string {
+	for value, s := range specialCardValues {
+		if s == special {
+			return value
+		}
+	}
+	return ""
+}
+
+// FormatScore returns the score as a card value.
+func FormatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+// legacySpecialScores are negative scores which legacy clients send in place of the special cards.
+var legacySpecialScores = map[float64]string{
+	-1: SpecialCardUnsure,
+	-2: SpecialCardBreak,
+	-3: SpecialCardInfinity,
+}
+
+// LegacyScoreValue returns the card value of the score, legacy clients send scores instead of values.
+func LegacyScoreValue(score float64) string {
+	if special, contains := legacySpecialScores[score]; contains {
+		return SpecialCardValue(special)
+	}
+	return FormatScore(score)
+}
+
+func parseScore(value string) (float64, bool) {
+	score, err := strconv.ParseFloat(value, 64)
+	return score, err == nil && !math.IsInf(score, 0) && !math.IsNaN(score)
+}
+
+func numericCards(scores ...float64) []DeckCard {
+	cards := make([]DeckCard, 0, len(scores))
+	for _, score := range scores {
+		cards = append(cards, DeckCard{Value: FormatScore(score), Score: score})
+	}
+	return cards
+}
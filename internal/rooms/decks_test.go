@@ -0,0 +1,28 @@
+package rooms
+
+import "testing"
+
+func TestLegacyScoreValue(t *testing.T) {
+	tests := []struct {
+		score       float64
+		wantValue   string
+		wantSpecial string
+	}{
+		{score: 5, wantValue: "5"},
+		{score: -1, wantValue: "?", wantSpecial: SpecialCardUnsure},
+		{score: -2, wantValue: "☕", wantSpecial: SpecialCardBreak},
+		{score: -3, wantValue: "∞", wantSpecial: SpecialCardInfinity},
+	}
+	for _, test := range tests {
+		t.Run(FormatScore(test.score), func(t *testing.T) {
+			value := LegacyScoreValue(test.score)
+			if value != test.wantValue {
+				t.Fatalf("LegacyScoreValue() = %q, want %q", value, test.wantValue)
+			}
+			card, ok := FindCard(DefaultDeck(), value)
+			if !ok || card.Special != test.wantSpecial {
+				t.Errorf("FindCard() = %v, %t, want a card of special type %q", card, ok, test.wantSpecial)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package rooms
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventRoomCreated   = "room_created"
+	EventRoomRestored  = "room_restored"
+	EventRoomCompacted = "room_compacted"
+	EventRoomDeleted   = "room_deleted"
+	EventPlayerJoined  = "player_joined"
+	EventPlayerRemoved = "player_removed"
+	EventPlayerLeft    = "player_left"
+	EventPlayerKicked  = "player_kicked"
+	EventPlayerUpdated = "player_updated"
+	EventPlayerErased  = "player_erased"
+
+	EventOwnerChanged      = "owner_changed"
+	EventPlayerRoleChanged = "player_role_changed"
+
+	EventInviteCodeCreated = "invite_code_created"
+	EventInviteCodeRevoked = "invite_code_revoked"
+
+	EventGameAdded     = "game_added"
+	EventGameCompleted = "game_completed"
+	EventGameReset     = "game_reset"
+	EventRoundStarted  = "round_started"
+	EventCardSent      = "card_sent"
+	EventCardDropped   = "card_dropped"
+
+	EventFinalEstimateChanged = "final_estimate_changed"
+
+	EventTimerStarted  = "timer_started"
+	EventTimerExtended = "timer_extended"
+	EventTimerCanceled = "timer_canceled"
+)
+
+// Event is an append-only record of a room change, the room state is derived by replaying
+// the events of the room in order of their sequence numbers.
+//
+// Events carry the payload of their type, like the sent card or the changed player, and are applied
+// to the state of the room by their type. Only checkpoints carry the whole room with its games:
+// the created room, a room restored from an old storage and a room_compacted event, which replaces
+// the oldest events of a long log by the state they produced.
+type Event struct {
+	RoomID    string    `json:"room_id"`
+	Sequence  int       `json:"sequence"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Commit    string    `json:"commit"`
+	// UserID is empty for changes made by the server itself.
+	UserID string `json:"user_id,omitempty"`
+	// PlayerID is set for changes of a player made by another user or the server.
+	PlayerID string `json:"player_id,omitempty"`
+	// GameID is set for changes of a game.
+	GameID string `json:"game_id,omitempty"`
+	// OwnerID is the player taking over the room from an erased owner.
+	OwnerID string `json:"owner_id,omitempty"`
+
+	// Room and Games are the state of the room at a checkpoint.
+	Room  *Room  `json:"room,omitempty"`
+	Games []Game `json:"games,omitempty"`
+
+	// Player is the joined, updated or the player with the changed role.
+	Player *Player `json:"player,omitempty"`
+	// Game is the added game.
+	Game *Game `json:"game,omitempty"`
+	// Card is the card sent to the game.
+	Card *Card `json:"card,omitempty"`
+	// InviteCode is the created invite code, only the code is set for a revoked code and a code used to join the room.
+	InviteCode *InviteCode `json:"invite_code,omitempty"`
+	// FinalEstimate is the change of the final estimate.
+	FinalEstimate *FinalEstimateChange `json:"final_estimate,omitempty"`
+	// Deadline is the deadline of the started or extended voting timer.
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// UnmarshalJSON also reads events stored before the fields were given JSON names, their keys are the field names.
+// Such events carry the resulting room and games of every change instead of a payload.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	type event Event
+	if err := json.Unmarshal(data, (*event)(e)); err != nil {
+		return err
+	}
+	if e.RoomID != "" {
+		return nil
+	}
+
+	var legacy struct {
+		RoomID    string
+		CreatedAt time.Time
+		UserID    string
+		PlayerID  string
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	e.RoomID = legacy.RoomID
+	e.CreatedAt = legacy.CreatedAt
+	e.UserID = legacy.UserID
+	e.PlayerID = legacy.PlayerID
+	return nil
+}
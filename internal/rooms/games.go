@@ -2,29 +2,133 @@ package rooms
 
 import (
 	"errors"
+	"slices"
+	"time"
 )
 
 const (
 	GameStatusActive    = "active"
 	GameStatusCompleted = "completed"
+
+	SpecialCardUnsure   = "unsure"
+	SpecialCardBreak    = "break"
+	SpecialCardInfinity = "infinity"
+)
+
+// SpecialCards are types of cards which can be sent with any deck, they aren't scored.
+var SpecialCards = []string{SpecialCardUnsure, SpecialCardBreak, SpecialCardInfinity}
+
+const (
+	maxAutoRevealDelay = 5 * time.Minute
+	maxTimerDuration   = time.Hour
 )
 
 var (
 	ErrGameNotFound      = errors.New("game not found")
 	ErrIllegalGameStatus = errors.New("illegal game status")
+	ErrInvalidAutoReveal = errors.New("invalid auto reveal")
+	ErrInvalidTimer      = errors.New("invalid timer duration")
 )
 
 type Game struct {
-	ID           string
-	RoomID       string
-	Name         string
-	Status       string
-	MaxScore     int
+	ID         string
+	RoomID     string
+	Name       string
+	Status     string
+	Deck       Deck
+	AutoReveal AutoReveal
+	// RevealAt is a time of completing the game by the auto reveal, it is zero if no reveal is pending.
+	RevealAt time.Time
+	// Deadline is a time of completing the game by the voting timer, it is zero if the timer isn't started.
+	Deadline     time.Time
+	MaxScore     float64
 	AverageScore int
-	Cards        []Card
+	// SpecialCardCounts are counts of special cards by their types, they are set on completion.
+	SpecialCardCounts map[string]int
+	// Statistics are set on completion, they are nil for games completed before statistics were introduced.
+	Statistics *Statistics
+	Cards      []Card
+	// Rounds are the previous voting rounds, the cards and the estimation of the game belong to the current round.
+	Rounds []Round
+	// FinalEstimate is a card value chosen by the facilitator, it is empty if it isn't chosen.
+	FinalEstimate        string
+	FinalEstimateChanges []FinalEstimateChange
+}
+
+// FinalEstimateChange is a record of choosing the final estimate of the game.
+type FinalEstimateChange struct {
+	Value     string
+	UserID    string
+	ChangedAt time.Time
+}
+
+// Round is a completed voting round archived by starting a new round of the game.
+type Round struct {
+	Cards             []Card
+	MaxScore          float64
+	AverageScore      int
+	SpecialCardCounts map[string]int
+	Statistics        *Statistics
+}
+
+// AutoReveal completes the game once every player who can vote has sent a card.
+type AutoReveal struct {
+	Enabled bool
+	// Delay lets players change their cards before the game is completed.
+	Delay time.Duration
+}
+
+func NewAutoReveal(enabled bool, delay time.Duration) (AutoReveal, error) {
+	if delay < 0 || delay > maxAutoRevealDelay {
+		return AutoReveal{}, ErrInvalidAutoReveal
+	}
+	return AutoReveal{Enabled: enabled, Delay: delay}, nil
+}
+
+// ValidateTimerDuration checks the duration of starting or extending the voting timer.
+func ValidateTimerDuration(duration time.Duration) error {
+	if duration < time.Second || duration > maxTimerDuration {
+		return ErrInvalidTimer
+	}
+	return nil
+}
+
+// ExtendDeadline returns the deadline of the voting timer extended by the duration,
+// the timer never ends later than the max timer duration from now however many times it is extended.
+func ExtendDeadline(deadline time.Time, duration time.Duration, now time.Time) time.Time {
+	extended := deadline.Add(duration)
+	if limit := now.Add(maxTimerDuration); extended.After(limit) {
+		return limit
+	}
+	return extended
 }
 
 type Card struct {
 	Player Player
-	Score  int
+	// Value is a value of the deck card, it is empty for cards sent before decks were introduced.
+	Value string
+	Score float64
+	// Special is a type of the special card, it is empty for scored cards.
+	Special string
+}
+
+// IsEveryoneVoted reports whether every player of the room who can vote has sent a card to the game,
+// observers aren't counted.
+func IsEveryoneVoted(room Room, game Game) bool {
+	voters := 0
+	for _, player := range room.Players {
+		if !HasPermission(player.Role, PermissionVote) {
+			continue
+		}
+		voters++
+		if !HasVoted(game, player.UserID) {
+			return false
+		}
+	}
+	return voters > 0
+}
+
+// HasVoted reports whether the user has sent a card to the game.
+func HasVoted(game Game, userID string) bool {
+	return slices.ContainsFunc(game.Cards, func(card Card) bool { return card.Player.UserID == userID })
 }
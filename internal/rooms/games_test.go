@@ -0,0 +1,27 @@
+package rooms
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtendDeadline(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		deadline time.Time
+		duration time.Duration
+		want     time.Time
+	}{
+		{name: "extended", deadline: now.Add(time.Minute), duration: 30 * time.Second, want: now.Add(90 * time.Second)},
+		{name: "up to the max duration", deadline: now.Add(50 * time.Minute), duration: 10 * time.Minute, want: now.Add(time.Hour)},
+		{name: "clamped to the max duration", deadline: now.Add(50 * time.Minute), duration: 30 * time.Minute, want: now.Add(time.Hour)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ExtendDeadline(test.deadline, test.duration, now); !got.Equal(test.want) {
+				t.Errorf("ExtendDeadline() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
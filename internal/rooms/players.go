@@ -4,4 +4,7 @@ type Player struct {
 	UserID string
 	Name   string
 	Color  string
+	Role   string
+	// Avatar is a URL of an image or an emoji, it is empty if the user hasn't set it.
+	Avatar string
 }
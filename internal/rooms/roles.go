@@ -0,0 +1,56 @@
+package rooms
+
+import (
+	"slices"
+)
+
+const (
+	RoleOwner     = "owner"
+	RoleModerator = "moderator"
+	RoleVoter     = "voter"
+	RoleObserver  = "observer"
+)
+
+type Permission int
+
+const (
+	// PermissionVote allows sending cards.
+	PermissionVote Permission = iota
+	// PermissionManageGames allows adding, completing and resetting games.
+	PermissionManageGames
+	// PermissionManagePlayers allows inviting players and removing voters and observers.
+	PermissionManagePlayers
+	// PermissionManageRoom allows deleting the room, changing roles of players and transferring the ownership.
+	PermissionManageRoom
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleOwner:     {PermissionVote, PermissionManageGames, PermissionManagePlayers, PermissionManageRoom},
+	RoleModerator: {PermissionVote, PermissionManageGames, PermissionManagePlayers},
+	RoleVoter:     {PermissionVote},
+	RoleObserver:  {},
+}
+
+func IsKnownRole(role string) bool {
+	_, contains := rolePermissions[role]
+	return contains
+}
+
+func HasPermission(role string, permission Permission) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// HasPlayerPermission checks whether the user is a player of the room with a role having the permission.
+func HasPlayerPermission(room Room, userID string, permission Permission) bool {
+	for _, player := range room.Players {
+		if player.UserID == userID {
+			return HasPermission(player.Role, permission)
+		}
+	}
+	return false
+}
+
+// IsSelectableRole checks whether users can choose the role for themselves on joining a room.
+func IsSelectableRole(role string) bool {
+	return role == RoleVoter || role == RoleObserver
+}
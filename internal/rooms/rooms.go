@@ -10,9 +10,12 @@ const (
 )
 
 var (
-	ErrForbidden     = errors.New("forbidden")
-	ErrRoomNotFound  = errors.New("room not found")
-	ErrLimitExceeded = errors.New("resource limit exceeded")
+	ErrForbidden          = errors.New("forbidden")
+	ErrRoomNotFound       = errors.New("room not found")
+	ErrInviteCodeNotFound = errors.New("invite code not found")
+	ErrPlayerNotFound     = errors.New("player not found")
+	ErrUnknownRole        = errors.New("unknown role")
+	ErrLimitExceeded      = errors.New("resource limit exceeded")
 )
 
 type RoomState struct {
@@ -23,16 +26,43 @@ type RoomState struct {
 type Room struct {
 	ID                 string
 	Commit             string
+	Sequence           int
 	Name               string
 	InviteCodeRequired bool
 	Owner              string
-	Players            []Player
-	InviteCodes        []InviteCode
-	Games              []string
-	VisitorsCount      int
+	// Deck is used by games added without a deck.
+	Deck Deck
+	// AutoReveal is used by games added without the auto reveal setting.
+	AutoReveal    AutoReveal
+	Players       []Player
+	InviteCodes   []InviteCode
+	Games         []string
+	VisitorsCount int
+}
+
+// InviteLink is a signed token which allows joining the room until it expires.
+type InviteLink struct {
+	Token     string
+	Role      string
+	ExpiresAt time.Time
 }
 
 type InviteCode struct {
 	Code      string
 	CreatedAt time.Time
+	// ExpiresAt is zero for codes without expiration.
+	ExpiresAt time.Time
+	// MaxUses is zero for codes without a limit of uses.
+	MaxUses int
+	Uses    int
+}
+
+// Erasure reports the rooms changed by erasing a user. The user is replaced by an anonymous player
+// in the history of the rooms, rooms owned by the user are passed to another player or deleted if it is empty.
+type Erasure struct {
+	LeftRooms        []string
+	TransferredRooms []string
+	DeletedRooms     []string
+	// AnonymizedCards is a number of cards of the user kept anonymously in the game results.
+	AnonymizedCards int
 }
@@ -0,0 +1,94 @@
+package roomsdata
+
+import (
+	"slices"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+)
+
+const (
+	erasedPlayerName  = "Deleted player"
+	erasedPlayerColor = "BDBDBD"
+)
+
+// anonymizeEvents returns a copy of the events with the user replaced by the anonymous player,
+// it reports whether the user was mentioned in the events.
+func anonymizeEvents(events []rooms.Event, userID string, anonymous rooms.Player) ([]rooms.Event, bool) {
+	mentioned := false
+	result := make([]rooms.Event, 0, len(events))
+	for _, event := range events {
+		if event.UserID == userID {
+			event.UserID = anonymous.UserID
+			mentioned = true
+		}
+		if event.PlayerID == userID {
+			event.PlayerID = anonymous.UserID
+			mentioned = true
+		}
+		if event.OwnerID == userID {
+			event.OwnerID = anonymous.UserID
+			mentioned = true
+		}
+		if event.Player != nil && event.Player.UserID == userID {
+			event.Player = anonymizePlayer(*event.Player, anonymous)
+			mentioned = true
+		}
+		if event.Card != nil && event.Card.Player.UserID == userID {
+			card := *event.Card
+			card.Player = *anonymizePlayer(card.Player, anonymous)
+			event.Card = &card
+			mentioned = true
+		}
+		if event.FinalEstimate != nil && event.FinalEstimate.UserID == userID {
+			change := *event.FinalEstimate
+			change.UserID = anonymous.UserID
+			event.FinalEstimate = &change
+			mentioned = true
+		}
+		if event.Room != nil {
+			room := *event.Room
+			if room.Owner == userID {
+				room.Owner = anonymous.UserID
+				mentioned = true
+			}
+			if idx := findPlayer(room.Players, userID); idx != rooms.UnknownIndex {
+				room.Players = slices.Clone(room.Players)
+				room.Players[idx] = *anonymizePlayer(room.Players[idx], anonymous)
+				mentioned = true
+			}
+			event.Room = &room
+		}
+		if len(event.Games) > 0 {
+			event.Games = slices.Clone(event.Games)
+			for i := range event.Games {
+				if updateUserCards(&event.Games[i], userID, anonymous) {
+					mentioned = true
+				}
+				if anonymizeFinalEstimateChanges(&event.Games[i], userID, anonymous.UserID) {
+					mentioned = true
+				}
+			}
+		}
+		result = append(result, event)
+	}
+	return result, mentioned
+}
+
+// anonymizePlayer returns the anonymous player with the role of the player.
+func anonymizePlayer(player rooms.Player, anonymous rooms.Player) *rooms.Player {
+	anonymous.Role = player.Role
+	return &anonymous
+}
+
+func anonymizeFinalEstimateChanges(game *rooms.Game, userID string, anonymousID string) bool {
+	if !slices.ContainsFunc(game.FinalEstimateChanges, func(change rooms.FinalEstimateChange) bool { return change.UserID == userID }) {
+		return false
+	}
+	game.FinalEstimateChanges = slices.Clone(game.FinalEstimateChanges)
+	for i, change := range game.FinalEstimateChanges {
+		if change.UserID == userID {
+			game.FinalEstimateChanges[i].UserID = anonymousID
+		}
+	}
+	return true
+}
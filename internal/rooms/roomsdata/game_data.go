@@ -1,47 +1,133 @@
 package roomsdata
 
 import (
+	"math"
 	"slices"
+	"time"
 
 	"aleksandersh.github.io/planning-poker-server/internal/rooms"
 )
 
+// estimateGame sets the max and average scores of the game, special cards aren't scored and only counted.
 func estimateGame(game rooms.Game) rooms.Game {
-	suggested := 0
-	sum := 0
+	maxScore := 0.0
+	sum := 0.0
 	count := 0
+	specialCounts := make(map[string]int)
 	for _, card := range game.Cards {
-		score := card.Score
-		if score >= 0 {
-			sum = sum + score
-			count = count + 1
-			if suggested >= 0 && suggested < score {
-				suggested = score
-			}
-		} else {
-			if suggested > score {
-				suggested = score
-			}
+		if card.Special != "" {
+			specialCounts[card.Special]++
+			continue
+		}
+		sum = sum + card.Score
+		count = count + 1
+		if count == 1 || maxScore < card.Score {
+			maxScore = card.Score
 		}
 	}
-	game.MaxScore = suggested
+	game.MaxScore = maxScore
 	if count > 0 {
-		average := sum / count
-		if sum%count > 0 {
-			average = average + 1
-		}
-		game.AverageScore = average
+		game.AverageScore = int(math.Ceil(sum / float64(count)))
 	} else {
 		game.AverageScore = 0
 	}
+	game.SpecialCardCounts = specialCounts
+	statistics := rooms.NewStatistics(game.Cards, game.Deck)
+	game.Statistics = &statistics
+	return game
+}
+
+// isLastGame reports whether the game is the last game of the room, which is shown as the current game.
+func isLastGame(room rooms.Room, gameID string) bool {
+	return len(room.Games) > 0 && room.Games[len(room.Games)-1] == gameID
+}
+
+func completeGame(game rooms.Game) rooms.Game {
+	game = estimateGame(game)
+	game.Status = rooms.GameStatusCompleted
+	game.RevealAt = time.Time{}
+	game.Deadline = time.Time{}
+	return game
+}
+
+// applyGameChange returns the game changed by the event of the room.
+func applyGameChange(room rooms.Room, game rooms.Game, event rooms.Event) rooms.Game {
+	switch event.Type {
+	case rooms.EventGameCompleted:
+		return completeGame(game)
+	case rooms.EventGameReset:
+		return resetGame(game, event.UserID, event.CreatedAt)
+	case rooms.EventRoundStarted:
+		game = archiveRound(game)
+		if game.FinalEstimate != "" {
+			game = setFinalEstimate(game, "", event.UserID, event.CreatedAt)
+		}
+	case rooms.EventCardSent:
+		game.Cards = putUserCard(game.Cards, *event.Card)
+		game = updateAutoReveal(room, game, event.CreatedAt)
+	case rooms.EventCardDropped:
+		game.Cards = dropUserCard(game.Cards, event.UserID)
+		game = updateAutoReveal(room, game, event.CreatedAt)
+	case rooms.EventFinalEstimateChanged:
+		game = setFinalEstimate(game, event.FinalEstimate.Value, event.FinalEstimate.UserID, event.FinalEstimate.ChangedAt)
+	case rooms.EventTimerStarted, rooms.EventTimerExtended:
+		game.Deadline = *event.Deadline
+	case rooms.EventTimerCanceled:
+		game.Deadline = time.Time{}
+	}
 	return game
 }
 
+// resetGame clears the cards and the estimation of the game, the game becomes active and its final estimate is cleared.
+func resetGame(game rooms.Game, userID string, now time.Time) rooms.Game {
+	game.Status = rooms.GameStatusActive
+	game.MaxScore = 0
+	game.AverageScore = 0
+	game.SpecialCardCounts = nil
+	game.Statistics = nil
+	game.RevealAt = time.Time{}
+	game.Deadline = time.Time{}
+	game.Cards = []rooms.Card{}
+	if game.FinalEstimate != "" {
+		game = setFinalEstimate(game, "", userID, now)
+	}
+	return game
+}
+
+// updateAutoReveal completes the active game or schedules its completion after the delay if the auto reveal
+// is enabled and every player who can vote has sent a card, the pending reveal is canceled otherwise.
+func updateAutoReveal(room rooms.Room, game rooms.Game, now time.Time) rooms.Game {
+	if game.Status != rooms.GameStatusActive || !game.AutoReveal.Enabled {
+		return game
+	}
+	if !rooms.IsEveryoneVoted(room, game) {
+		game.RevealAt = time.Time{}
+		return game
+	}
+	if game.AutoReveal.Delay == 0 {
+		return completeGame(game)
+	}
+	if game.RevealAt.IsZero() {
+		game.RevealAt = now.Add(game.AutoReveal.Delay)
+	}
+	return game
+}
+
+// isGameDue reports whether the pending auto reveal or the voting timer of the active game is due by the time.
+func isGameDue(game rooms.Game, now time.Time) bool {
+	isRevealDue := !game.RevealAt.IsZero() && !now.Before(game.RevealAt)
+	isDeadlineDue := !game.Deadline.IsZero() && !now.Before(game.Deadline)
+	return game.Status == rooms.GameStatusActive && (isRevealDue || isDeadlineDue)
+}
+
+// putUserCard returns a copy of the cards with the card of the user replaced or added,
+// stored games are shared with readers outside the lock so they are never modified in place.
 func putUserCard(cards []rooms.Card, card rooms.Card) []rooms.Card {
+	cards = slices.Clone(cards)
 	idx := slices.IndexFunc(cards, func(c rooms.Card) bool {
 		return c.Player.UserID == card.Player.UserID
 	})
-	if idx > 0 {
+	if idx >= 0 {
 		cards[idx] = card
 	} else {
 		cards = append(cards, card)
@@ -50,7 +136,80 @@ func putUserCard(cards []rooms.Card, card rooms.Card) []rooms.Card {
 }
 
 func dropUserCard(cards []rooms.Card, userID string) []rooms.Card {
-	return slices.DeleteFunc(cards, func(card rooms.Card) bool {
+	return slices.DeleteFunc(slices.Clone(cards), func(card rooms.Card) bool {
+		return card.Player.UserID == userID
+	})
+}
+
+// updateUserCards replaces the player of the cards sent by the user in every round of the game,
+// it reports whether any card was changed. Cards which already have the player aren't copied.
+func updateUserCards(game *rooms.Game, userID string, player rooms.Player) bool {
+	changed := false
+	if cards, ok := replaceCardPlayer(game.Cards, userID, player); ok {
+		game.Cards = cards
+		changed = true
+	}
+	rounds := slices.Clone(game.Rounds)
+	for i, round := range rounds {
+		if cards, ok := replaceCardPlayer(round.Cards, userID, player); ok {
+			rounds[i].Cards = cards
+			game.Rounds = rounds
+			changed = true
+		}
+	}
+	return changed
+}
+
+// countUserCards returns the number of cards sent by the user in every round of the game.
+func countUserCards(game rooms.Game, userID string) int {
+	count := 0
+	if rooms.HasVoted(game, userID) {
+		count++
+	}
+	for _, round := range game.Rounds {
+		if slices.ContainsFunc(round.Cards, func(card rooms.Card) bool { return card.Player.UserID == userID }) {
+			count++
+		}
+	}
+	return count
+}
+
+func replaceCardPlayer(cards []rooms.Card, userID string, player rooms.Player) ([]rooms.Card, bool) {
+	idx := slices.IndexFunc(cards, func(card rooms.Card) bool {
 		return card.Player.UserID == userID
 	})
+	if idx == rooms.UnknownIndex || cards[idx].Player == player {
+		return cards, false
+	}
+	cards = slices.Clone(cards)
+	cards[idx].Player = player
+	return cards, true
+}
+
+// setFinalEstimate changes the final estimate of the game and records the change to its history.
+func setFinalEstimate(game rooms.Game, value string, userID string, now time.Time) rooms.Game {
+	game.FinalEstimate = value
+	change := rooms.FinalEstimateChange{Value: value, UserID: userID, ChangedAt: now}
+	game.FinalEstimateChanges = append(slices.Clone(game.FinalEstimateChanges), change)
+	return game
+}
+
+// archiveRound moves the cards and the estimation of the completed game to a new round,
+// the game becomes active for the next round.
+func archiveRound(game rooms.Game) rooms.Game {
+	round := rooms.Round{
+		Cards:             game.Cards,
+		MaxScore:          game.MaxScore,
+		AverageScore:      game.AverageScore,
+		SpecialCardCounts: game.SpecialCardCounts,
+		Statistics:        game.Statistics,
+	}
+	game.Rounds = append(slices.Clone(game.Rounds), round)
+	game.Status = rooms.GameStatusActive
+	game.MaxScore = 0
+	game.AverageScore = 0
+	game.SpecialCardCounts = nil
+	game.Statistics = nil
+	game.Cards = []rooms.Card{}
+	return game
 }
@@ -0,0 +1,61 @@
+package roomsdata
+
+import (
+	"testing"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+)
+
+func TestPutUserCard(t *testing.T) {
+	alice := rooms.Player{UserID: "alice"}
+	bob := rooms.Player{UserID: "bob"}
+	tests := []struct {
+		name  string
+		cards []rooms.Card
+		card  rooms.Card
+		want  []rooms.Card
+	}{
+		{
+			name:  "adds the card of a new player",
+			cards: []rooms.Card{{Player: alice, Value: "1"}},
+			card:  rooms.Card{Player: bob, Value: "2"},
+			want:  []rooms.Card{{Player: alice, Value: "1"}, {Player: bob, Value: "2"}},
+		},
+		{
+			name:  "replaces the card of the first player",
+			cards: []rooms.Card{{Player: alice, Value: "1"}, {Player: bob, Value: "2"}},
+			card:  rooms.Card{Player: alice, Value: "3"},
+			want:  []rooms.Card{{Player: alice, Value: "3"}, {Player: bob, Value: "2"}},
+		},
+		{
+			name:  "replaces the card of the last player",
+			cards: []rooms.Card{{Player: alice, Value: "1"}, {Player: bob, Value: "2"}},
+			card:  rooms.Card{Player: bob, Value: "5"},
+			want:  []rooms.Card{{Player: alice, Value: "1"}, {Player: bob, Value: "5"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original := append([]rooms.Card(nil), test.cards...)
+			got := putUserCard(test.cards, test.card)
+			if !equalCards(got, test.want) {
+				t.Errorf("putUserCard() = %v, want %v", got, test.want)
+			}
+			if !equalCards(test.cards, original) {
+				t.Errorf("putUserCard() modified the cards in place: %v", test.cards)
+			}
+		})
+	}
+}
+
+func equalCards(a []rooms.Card, b []rooms.Card) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Player.UserID != b[i].Player.UserID || a[i].Value != b[i].Value {
+			return false
+		}
+	}
+	return true
+}
@@ -1,9 +1,12 @@
 package roomsdata
 
 import (
+	"fmt"
 	"log"
+	"slices"
 	"strconv"
 	"sync"
+	"time"
 
 	"aleksandersh.github.io/planning-poker-server/internal/rooms"
 	"aleksandersh.github.io/planning-poker-server/internal/users"
@@ -15,6 +18,13 @@ const (
 	playersLimit  = 300
 	sessionsLimit = 60
 	gamesLimit    = 200
+
+	inviteCodesLimit = 50
+
+	// eventsLimit is a number of events of the room which triggers the compaction of its log,
+	// the events except the last eventsKept are replaced by a single event with the state they produced.
+	eventsLimit = 1000
+	eventsKept  = 500
 )
 
 var playerColors = []string{
@@ -28,19 +38,42 @@ var playerColors = []string{
 }
 
 type Repository struct {
-	mutex sync.RWMutex
-	rooms map[string]rooms.Room
-	games map[string]rooms.Game
+	mutex   sync.RWMutex
+	storage Storage
+	rooms   map[string]rooms.Room
+	games   map[string]rooms.Game
+	events  map[string][]rooms.Event
+	changes map[string]chan struct{}
 }
 
 func NewRepo() *Repository {
 	return &Repository{
-		rooms: make(map[string]rooms.Room),
-		games: make(map[string]rooms.Game),
+		storage: memoryStorage{},
+		rooms:   make(map[string]rooms.Room),
+		games:   make(map[string]rooms.Game),
+		events:  make(map[string][]rooms.Event),
+		changes: make(map[string]chan struct{}),
 	}
 }
 
-func (r *Repository) Create(user users.User, name string, inviteCodeRequired bool) (rooms.Room, error) {
+// NewPersistentRepo creates a repository which replays the events of the storage
+// and writes every new event through it.
+func NewPersistentRepo(storage Storage) (*Repository, error) {
+	r := NewRepo()
+	r.storage = storage
+
+	events, err := storage.LoadEvents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+	for _, event := range events {
+		r.applyEvent(event)
+	}
+
+	return r, nil
+}
+
+func (r *Repository) Create(user users.User, name string, inviteCodeRequired bool, deck rooms.Deck, autoReveal rooms.AutoReveal) (rooms.Room, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -48,19 +81,18 @@ func (r *Repository) Create(user users.User, name string, inviteCodeRequired boo
 		return rooms.Room{}, rooms.ErrLimitExceeded
 	}
 
-	id := r.createRoomID()
 	room := rooms.Room{
-		ID:                 id,
-		Commit:             idutils.GenerateID(),
+		ID:                 r.createRoomID(),
 		Name:               name,
 		InviteCodeRequired: inviteCodeRequired,
 		Owner:              user.ID,
-		Players:            []rooms.Player{newPlayer(user, 0)},
+		Deck:               deck,
+		AutoReveal:         autoReveal,
+		Players:            []rooms.Player{newPlayer(user, 0, rooms.RoleOwner)},
 		Games:              []string{},
 		VisitorsCount:      1,
 	}
-	r.rooms[id] = room
-	return room, nil
+	return r.commitEvent(rooms.Event{Type: rooms.EventRoomCreated, RoomID: room.ID, UserID: user.ID, Room: &room})
 }
 
 func (r *Repository) Get(userID string, roomID string) (rooms.Room, error) {
@@ -78,39 +110,237 @@ func (r *Repository) Delete(userID string, roomID string) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	_, err := r.getOwnedRoom(userID, roomID)
-	if err != nil {
+	if _, err := r.getPermittedRoom(userID, roomID, rooms.PermissionManageRoom); err != nil {
 		return err
 	}
 
-	delete(r.rooms, roomID)
-	return nil
+	_, err := r.commitEvent(rooms.Event{Type: rooms.EventRoomDeleted, RoomID: roomID, UserID: userID})
+	return err
 }
 
-func (r *Repository) Join(user users.User, roomID string, inviteCode string) (rooms.Room, error) {
+// Join adds the user to the room with the role chosen by the user, the voter role is given if the role is empty.
+func (r *Repository) Join(user users.User, roomID string, inviteCode string, role string) (rooms.Room, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	if role == "" {
+		role = rooms.RoleVoter
+	}
+	if !rooms.IsSelectableRole(role) {
+		return rooms.Room{}, rooms.ErrUnknownRole
+	}
 	room, contains := r.rooms[roomID]
 	if !contains {
 		return rooms.Room{}, rooms.ErrRoomNotFound
 	}
-	if isPlayerExists(room, user.ID) || !isInviteCodeAccepted(room, inviteCode) {
+	if isPlayerExists(room, user.ID) {
 		return rooms.Room{}, rooms.ErrForbidden
 	}
+	usedCode := ""
+	if room.InviteCodeRequired {
+		idx := findInviteCode(room, inviteCode, time.Now())
+		if idx == rooms.UnknownIndex {
+			return rooms.Room{}, rooms.ErrForbidden
+		}
+		usedCode = room.InviteCodes[idx].Code
+	}
 
-	room.Players = append(room.Players, newPlayer(user, room.VisitorsCount))
-	room.VisitorsCount = room.VisitorsCount + 1
-	r.saveRoom(room)
+	return r.join(user, room, role, usedCode)
+}
 
-	return room, nil
+// JoinInvited adds the user to the room with the role without checking invite codes, it is used for verified invitations.
+// The voter role is given if the role is empty.
+func (r *Repository) JoinInvited(user users.User, roomID string, role string) (rooms.Room, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, contains := r.rooms[roomID]
+	if !contains {
+		return rooms.Room{}, rooms.ErrRoomNotFound
+	}
+	if isPlayerExists(room, user.ID) {
+		return rooms.Room{}, rooms.ErrForbidden
+	}
+
+	if role == "" {
+		role = rooms.RoleVoter
+	}
+	if role == rooms.RoleOwner || !rooms.IsKnownRole(role) {
+		return rooms.Room{}, rooms.ErrUnknownRole
+	}
+
+	return r.join(user, room, role, "")
+}
+
+// join adds the user to the room, the use of the invite code is counted if it is given.
+func (r *Repository) join(user users.User, room rooms.Room, role string, inviteCode string) (rooms.Room, error) {
+	if len(room.Players) >= playersLimit {
+		return rooms.Room{}, rooms.ErrLimitExceeded
+	}
+	player := newPlayer(user, room.VisitorsCount, role)
+	event := rooms.Event{Type: rooms.EventPlayerJoined, RoomID: room.ID, UserID: user.ID, Player: &player}
+	if inviteCode != "" {
+		event.InviteCode = &rooms.InviteCode{Code: inviteCode}
+	}
+	return r.commitEvent(event)
+}
+
+// Leave removes the user from the room and drops the card of the user in the active game.
+// The room owner can't leave the room.
+func (r *Repository) Leave(userID string, roomID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, contains := r.rooms[roomID]
+	if !contains {
+		return rooms.ErrRoomNotFound
+	}
+	if room.Owner == userID || !isPlayerExists(room, userID) {
+		return rooms.ErrForbidden
+	}
+
+	_, err := r.commitEvent(rooms.Event{Type: rooms.EventPlayerLeft, RoomID: roomID, UserID: userID})
+	return err
+}
+
+// Kick removes the player from the room on behalf of the owner or a moderator and drops the card of the player in the active game.
+func (r *Repository) Kick(userID string, roomID string, playerID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, err := r.getPermittedRoom(userID, roomID, rooms.PermissionManagePlayers)
+	if err != nil {
+		return err
+	}
+	player, err := getPlayer(room, playerID)
+	if err != nil {
+		return rooms.ErrPlayerNotFound
+	}
+	if player.Role == rooms.RoleOwner {
+		return rooms.ErrForbidden
+	}
+	if player.Role == rooms.RoleModerator && !rooms.HasPlayerPermission(room, userID, rooms.PermissionManageRoom) {
+		return rooms.ErrForbidden
+	}
+
+	_, err = r.commitEvent(rooms.Event{Type: rooms.EventPlayerKicked, RoomID: roomID, UserID: userID, PlayerID: playerID})
+	return err
+}
+
+// TransferOwnership makes the player the owner of the room, the previous owner becomes a moderator.
+func (r *Repository) TransferOwnership(userID string, roomID string, playerID string) (rooms.Room, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, err := r.getPermittedRoom(userID, roomID, rooms.PermissionManageRoom)
+	if err != nil {
+		return rooms.Room{}, err
+	}
+	if !isPlayerExists(room, playerID) {
+		return rooms.Room{}, rooms.ErrPlayerNotFound
+	}
+	if room.Owner == playerID {
+		return room, nil
+	}
+
+	return r.commitEvent(rooms.Event{Type: rooms.EventOwnerChanged, RoomID: roomID, UserID: userID, PlayerID: playerID})
+}
+
+// SetPlayerRole changes the role of the player, the owner role is given only by the ownership transfer.
+// The card of the player is dropped from the active game if the new role can't vote.
+func (r *Repository) SetPlayerRole(userID string, roomID string, playerID string, role string) (rooms.Room, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !rooms.IsKnownRole(role) {
+		return rooms.Room{}, rooms.ErrUnknownRole
+	}
+	room, err := r.getPermittedRoom(userID, roomID, rooms.PermissionManageRoom)
+	if err != nil {
+		return rooms.Room{}, err
+	}
+	idx := findPlayer(room.Players, playerID)
+	if idx == rooms.UnknownIndex {
+		return rooms.Room{}, rooms.ErrPlayerNotFound
+	}
+	if role == rooms.RoleOwner || room.Players[idx].Role == rooms.RoleOwner {
+		return rooms.Room{}, rooms.ErrForbidden
+	}
+	if room.Players[idx].Role == role {
+		return room, nil
+	}
+
+	player := room.Players[idx]
+	player.Role = role
+	return r.commitEvent(rooms.Event{Type: rooms.EventPlayerRoleChanged, RoomID: roomID, UserID: userID, PlayerID: playerID, Player: &player})
+}
+
+// CreateInviteCode adds a new invite code to the room, the expired and used up codes are removed.
+func (r *Repository) CreateInviteCode(userID string, roomID string, expiresAt time.Time, maxUses int) (rooms.InviteCode, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, err := r.getPermittedRoom(userID, roomID, rooms.PermissionManagePlayers)
+	if err != nil {
+		return rooms.InviteCode{}, err
+	}
+
+	now := time.Now()
+	if len(removeInvalidInviteCodes(room.InviteCodes, now)) >= inviteCodesLimit {
+		return rooms.InviteCode{}, rooms.ErrLimitExceeded
+	}
+
+	code := rooms.InviteCode{
+		Code:      r.createInviteCode(room),
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+		MaxUses:   maxUses,
+	}
+	_, err = r.commitEvent(rooms.Event{Type: rooms.EventInviteCodeCreated, RoomID: roomID, UserID: userID, InviteCode: &code})
+	if err != nil {
+		return rooms.InviteCode{}, err
+	}
+	return code, nil
 }
 
-func (r *Repository) AddGame(userID string, roomID string, game rooms.Game) (rooms.Game, error) {
+func (r *Repository) GetInviteCodes(userID string, roomID string) ([]rooms.InviteCode, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	room, err := r.getPermittedRoom(userID, roomID, rooms.PermissionManagePlayers)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(room.InviteCodes), nil
+}
+
+func (r *Repository) RevokeInviteCode(userID string, roomID string, inviteCode string) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	room, err := r.getOwnedRoom(userID, roomID)
+	room, err := r.getPermittedRoom(userID, roomID, rooms.PermissionManagePlayers)
+	if err != nil {
+		return err
+	}
+
+	idx := slices.IndexFunc(room.InviteCodes, func(code rooms.InviteCode) bool {
+		return code.Code == inviteCode
+	})
+	if idx == rooms.UnknownIndex {
+		return rooms.ErrInviteCodeNotFound
+	}
+
+	code := rooms.InviteCode{Code: inviteCode}
+	_, err = r.commitEvent(rooms.Event{Type: rooms.EventInviteCodeRevoked, RoomID: roomID, UserID: userID, InviteCode: &code})
+	return err
+}
+
+// AddGame adds the game to the room, the deck and the auto reveal of the room are used if they aren't given.
+func (r *Repository) AddGame(userID string, roomID string, game rooms.Game, autoReveal *rooms.AutoReveal) (rooms.Game, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, err := r.getPermittedRoom(userID, roomID, rooms.PermissionManageGames)
 	if err != nil {
 		return rooms.Game{}, err
 	}
@@ -124,20 +354,25 @@ func (r *Repository) AddGame(userID string, roomID string, game rooms.Game) (roo
 	if len(game.Name) == 0 {
 		game.Name = "Game " + strconv.Itoa(len(room.Games)+1)
 	}
+	if len(game.Deck.Cards) == 0 {
+		game.Deck = room.Deck
+	}
+	if len(game.Deck.Cards) == 0 {
+		game.Deck = rooms.DefaultDeck()
+	}
+	game.AutoReveal = room.AutoReveal
+	if autoReveal != nil {
+		game.AutoReveal = *autoReveal
+	}
 
-	r.games[game.ID] = game
-
-	room.Games = append(room.Games, game.ID)
-	r.saveRoom(room)
-
-	return game, nil
+	return r.commitGameEvent(rooms.Event{Type: rooms.EventGameAdded, UserID: userID, Game: &game}, game)
 }
 
 func (r *Repository) CompleteGame(userID string, gameID string) (rooms.Game, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	room, game, err := r.getRoomAndGame(userID, gameID)
+	_, game, err := r.getRoomAndGame(userID, gameID, rooms.PermissionManageGames)
 	if err != nil {
 		return game, err
 	}
@@ -146,41 +381,54 @@ func (r *Repository) CompleteGame(userID string, gameID string) (rooms.Game, err
 		return game, nil
 	}
 
-	game = estimateGame(game)
-	game.Status = rooms.GameStatusCompleted
-	r.games[game.ID] = game
+	return r.commitGameEvent(rooms.Event{Type: rooms.EventGameCompleted, UserID: userID}, game)
+}
+
+func (r *Repository) ResetGame(userID string, gameID string) (rooms.Game, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
-	r.saveRoom(room)
+	_, game, err := r.getRoomAndGame(userID, gameID, rooms.PermissionManageGames)
+	if err != nil {
+		return game, err
+	}
 
-	return game, nil
+	return r.commitGameEvent(rooms.Event{Type: rooms.EventGameReset, UserID: userID}, game)
 }
 
-func (r *Repository) ResetGame(userID string, gameID string) (rooms.Game, error) {
+// SendCard puts the card of the player to the game, the card value must be in the deck of the game.
+func (r *Repository) SendCard(userID string, gameID string, value string) (rooms.Game, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	room, game, err := r.getRoomAndGame(userID, gameID)
+	room, game, err := r.getRoomAndGame(userID, gameID, rooms.PermissionVote)
 	if err != nil {
 		return game, err
 	}
 
-	game = estimateGame(game)
-	game.Status = rooms.GameStatusActive
-	game.MaxScore = 0
-	game.AverageScore = 0
-	game.Cards = []rooms.Card{}
-	r.games[game.ID] = game
+	if game.Status == rooms.GameStatusCompleted {
+		return game, rooms.ErrIllegalGameStatus
+	}
 
-	r.saveRoom(room)
+	player, err := getPlayer(room, userID)
+	if err != nil {
+		return game, err
+	}
+
+	card, ok := rooms.FindCard(game.Deck, value)
+	if !ok {
+		return game, rooms.ErrInvalidCard
+	}
 
-	return game, nil
+	sent := rooms.Card{Player: player, Value: card.Value, Score: card.Score, Special: card.Special}
+	return r.commitGameEvent(rooms.Event{Type: rooms.EventCardSent, UserID: userID, Card: &sent}, game)
 }
 
-func (r *Repository) SendCard(userID string, gameID string, score int) (rooms.Game, error) {
+func (r *Repository) DropCard(userID string, gameID string) (rooms.Game, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	room, game, err := r.getRoomAndGame(userID, gameID)
+	_, game, err := r.getRoomAndGame(userID, gameID, rooms.PermissionVote)
 	if err != nil {
 		return game, err
 	}
@@ -189,41 +437,261 @@ func (r *Repository) SendCard(userID string, gameID string, score int) (rooms.Ga
 		return game, rooms.ErrIllegalGameStatus
 	}
 
-	player, err := getPlayer(room, userID)
+	return r.commitGameEvent(rooms.Event{Type: rooms.EventCardDropped, UserID: userID}, game)
+}
+
+// StartRound archives the current round of the completed game and starts a new round, the final estimate is cleared.
+// Only the last game of the room can be voted again, since earlier games are never active.
+func (r *Repository) StartRound(userID string, gameID string) (rooms.Game, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, game, err := r.getRoomAndGame(userID, gameID, rooms.PermissionManageGames)
 	if err != nil {
 		return game, err
 	}
+	if game.Status != rooms.GameStatusCompleted || !isLastGame(room, gameID) {
+		return game, rooms.ErrIllegalGameStatus
+	}
 
-	game.Cards = putUserCard(game.Cards, rooms.Card{Player: player, Score: score})
-	r.games[game.ID] = game
+	return r.commitGameEvent(rooms.Event{Type: rooms.EventRoundStarted, UserID: userID}, game)
+}
+
+// SetFinalEstimate chooses the final estimate of the completed game, it is a value of the deck or a special card.
+// The empty value clears the final estimate.
+func (r *Repository) SetFinalEstimate(userID string, gameID string, value string) (rooms.Game, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
-	r.saveRoom(room)
+	_, game, err := r.getRoomAndGame(userID, gameID, rooms.PermissionManageGames)
+	if err != nil {
+		return game, err
+	}
+	if game.Status != rooms.GameStatusCompleted {
+		return game, rooms.ErrIllegalGameStatus
+	}
+	if len(value) > 0 {
+		card, ok := rooms.FindCard(game.Deck, value)
+		if !ok {
+			return game, rooms.ErrInvalidCard
+		}
+		value = card.Value
+	}
 
-	return game, nil
+	change := rooms.FinalEstimateChange{Value: value, UserID: userID, ChangedAt: time.Now()}
+	return r.commitGameEvent(rooms.Event{Type: rooms.EventFinalEstimateChanged, UserID: userID, FinalEstimate: &change}, game)
 }
 
-func (r *Repository) DropCard(userID string, gameID string) (rooms.Game, error) {
+// StartTimer starts the voting timer of the active game, the running timer is restarted.
+func (r *Repository) StartTimer(userID string, gameID string, duration time.Duration) (rooms.Game, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	room, game, err := r.getRoomAndGame(userID, gameID)
+	_, game, err := r.getRoomAndGame(userID, gameID, rooms.PermissionManageGames)
 	if err != nil {
 		return game, err
 	}
+	if game.Status != rooms.GameStatusActive {
+		return game, rooms.ErrIllegalGameStatus
+	}
 
-	if game.Status == rooms.GameStatusCompleted {
+	deadline := time.Now().Add(duration)
+	return r.commitGameEvent(rooms.Event{Type: rooms.EventTimerStarted, UserID: userID, Deadline: &deadline}, game)
+}
+
+// ExtendTimer moves the deadline of the running voting timer.
+func (r *Repository) ExtendTimer(userID string, gameID string, duration time.Duration) (rooms.Game, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, game, err := r.getRoomAndGame(userID, gameID, rooms.PermissionManageGames)
+	if err != nil {
+		return game, err
+	}
+	if game.Status != rooms.GameStatusActive || game.Deadline.IsZero() {
 		return game, rooms.ErrIllegalGameStatus
 	}
 
-	game.Cards = dropUserCard(game.Cards, userID)
-	r.games[game.ID] = game
+	deadline := rooms.ExtendDeadline(game.Deadline, duration, time.Now())
+	return r.commitGameEvent(rooms.Event{Type: rooms.EventTimerExtended, UserID: userID, Deadline: &deadline}, game)
+}
 
-	r.saveRoom(room)
+func (r *Repository) CancelTimer(userID string, gameID string) (rooms.Game, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, game, err := r.getRoomAndGame(userID, gameID, rooms.PermissionManageGames)
+	if err != nil {
+		return game, err
+	}
+	if game.Status != rooms.GameStatusActive || game.Deadline.IsZero() {
+		return game, rooms.ErrIllegalGameStatus
+	}
+
+	return r.commitGameEvent(rooms.Event{Type: rooms.EventTimerCanceled, UserID: userID}, game)
+}
+
+// CompleteDueGames completes the games whose pending auto reveal or voting timer is due by the time.
+func (r *Repository) CompleteDueGames(now time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
-	return game, nil
+	for _, room := range r.rooms {
+		game, contains := r.getActiveGame(room)
+		if !contains || !isGameDue(game, now) {
+			continue
+		}
+		if _, err := r.commitGameEvent(rooms.Event{Type: rooms.EventGameCompleted}, game); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetRooms returns all rooms, the access is not checked.
+func (r *Repository) GetRooms() []rooms.Room {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	roomsList := make([]rooms.Room, 0, len(r.rooms))
+	for _, room := range r.rooms {
+		roomsList = append(roomsList, room)
+	}
+	return roomsList
+}
+
+// RemoveRoom deletes the room on behalf of the server, the access is not checked.
+func (r *Repository) RemoveRoom(roomID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.isRoomExists(roomID) {
+		return rooms.ErrRoomNotFound
+	}
+
+	_, err := r.commitEvent(rooms.Event{Type: rooms.EventRoomDeleted, RoomID: roomID})
+	return err
+}
+
+// RemovePlayer removes the player from the room on behalf of the server and drops the card
+// of the player in the active game. The room owner can't be removed.
+func (r *Repository) RemovePlayer(roomID string, userID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	room, contains := r.rooms[roomID]
+	if !contains {
+		return rooms.ErrRoomNotFound
+	}
+	if room.Owner == userID || !isPlayerExists(room, userID) {
+		return rooms.ErrForbidden
+	}
+
+	_, err := r.commitEvent(rooms.Event{Type: rooms.EventPlayerRemoved, RoomID: roomID, PlayerID: userID})
+	return err
+}
+
+// UpdatePlayer applies the profile of the user to the player in every room the user has joined
+// and to the cards the user has sent in the games of these rooms, rooms where the player is unchanged are skipped.
+func (r *Repository) UpdatePlayer(user users.User) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, room := range r.rooms {
+		idx := findPlayer(room.Players, user.ID)
+		if idx == rooms.UnknownIndex {
+			continue
+		}
+
+		player := updatePlayer(room.Players[idx], user)
+		if player == room.Players[idx] {
+			continue
+		}
+		event := rooms.Event{Type: rooms.EventPlayerUpdated, RoomID: room.ID, UserID: user.ID, Player: &player}
+		if _, err := r.commitEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EraseUser removes the user from every room and replaces the user with an anonymous player
+// in the room history, so the game results are kept without personal data. Rooms owned by the user
+// are passed to the next player or deleted together with their history if there are no other players.
+func (r *Repository) EraseUser(userID string) (rooms.Erasure, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	roomIDs := make([]string, 0, len(r.rooms))
+	for roomID := range r.rooms {
+		roomIDs = append(roomIDs, roomID)
+	}
+
+	var erasure rooms.Erasure
+	for _, roomID := range roomIDs {
+		room := r.rooms[roomID]
+		nextOwner := ""
+		if room.Owner == userID {
+			nextOwner = findNextOwner(room)
+			if nextOwner == "" {
+				if err := r.replaceEvents(roomID, nil); err != nil {
+					return erasure, err
+				}
+				erasure.DeletedRooms = append(erasure.DeletedRooms, roomID)
+				continue
+			}
+		}
+
+		anonymous := rooms.Player{UserID: idutils.GenerateID(), Name: erasedPlayerName, Color: erasedPlayerColor}
+		events, mentioned := anonymizeEvents(r.events[roomID], userID, anonymous)
+		if !mentioned {
+			continue
+		}
+		if err := r.replaceEvents(roomID, events); err != nil {
+			return erasure, err
+		}
+
+		event := rooms.Event{Type: rooms.EventPlayerErased, RoomID: roomID, PlayerID: anonymous.UserID}
+		if isPlayerExists(r.rooms[roomID], anonymous.UserID) {
+			if nextOwner != "" {
+				event.OwnerID = nextOwner
+				erasure.TransferredRooms = append(erasure.TransferredRooms, roomID)
+			} else {
+				erasure.LeftRooms = append(erasure.LeftRooms, roomID)
+			}
+		}
+		if _, err := r.commitEvent(event); err != nil {
+			return erasure, err
+		}
+
+		for _, gameID := range r.rooms[roomID].Games {
+			erasure.AnonymizedCards += countUserCards(r.games[gameID], anonymous.UserID)
+		}
+	}
+	return erasure, nil
 }
 
 func (r *Repository) GetRoomState(userID string, roomID string) (rooms.RoomState, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.getRoomState(userID, roomID)
+}
+
+// ObserveRoomState returns the current room state together with a channel
+// that is closed on the next change of the room (including its deletion).
+func (r *Repository) ObserveRoomState(userID string, roomID string) (rooms.RoomState, <-chan struct{}, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	roomState, err := r.getRoomState(userID, roomID)
+	if err != nil {
+		return roomState, nil, err
+	}
+	return roomState, r.changes[roomID], nil
+}
+
+func (r *Repository) getRoomState(userID string, roomID string) (rooms.RoomState, error) {
 	room, contains := r.rooms[roomID]
 	if !contains {
 		return rooms.RoomState{}, rooms.ErrRoomNotFound
@@ -243,6 +711,38 @@ func (r *Repository) GetRoomState(userID string, roomID string) (rooms.RoomState
 	return rooms.RoomState{Room: room, Games: games}, nil
 }
 
+// GetEvents returns the events of the room with sequence numbers greater than the given one.
+func (r *Repository) GetEvents(userID string, roomID string, afterSequence int) ([]rooms.Event, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	room, contains := r.rooms[roomID]
+	if !contains {
+		return nil, rooms.ErrRoomNotFound
+	}
+	if !isPlayerExists(room, userID) {
+		return nil, rooms.ErrForbidden
+	}
+
+	events := r.events[roomID]
+	idx, _ := slices.BinarySearchFunc(events, afterSequence+1, func(event rooms.Event, sequence int) int {
+		return event.Sequence - sequence
+	})
+	return slices.Clone(events[idx:]), nil
+}
+
+// Export returns the events of all existing rooms, the events of every room are in order.
+func (r *Repository) Export() []rooms.Event {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	events := make([]rooms.Event, 0)
+	for _, roomEvents := range r.events {
+		events = append(events, roomEvents...)
+	}
+	return events
+}
+
 func (r *Repository) createRoomID() string {
 	counter := 0
 	id := generateRoomID()
@@ -256,6 +756,19 @@ func (r *Repository) createRoomID() string {
 	return id
 }
 
+func (r *Repository) createInviteCode(room rooms.Room) string {
+	counter := 0
+	code := generateInviteCode()
+	for slices.ContainsFunc(room.InviteCodes, func(c rooms.InviteCode) bool { return c.Code == code }) {
+		counter++
+		if counter == 10_000 {
+			log.Panicf("too many attempts to generate next invite code")
+		}
+		code = generateInviteCode()
+	}
+	return code
+}
+
 func (r *Repository) isRoomExists(id string) bool {
 	_, contains := r.rooms[id]
 	return contains
@@ -279,28 +792,77 @@ func (r *Repository) isGameExists(id string) bool {
 	return contains
 }
 
-func (r *Repository) getOwnedRoom(userID string, roomID string) (rooms.Room, error) {
+// getPermittedRoom returns the room if the user is a player of the room with a role having the permission.
+func (r *Repository) getPermittedRoom(userID string, roomID string, permission rooms.Permission) (rooms.Room, error) {
 	room, contains := r.rooms[roomID]
 	if !contains {
 		return rooms.Room{}, rooms.ErrRoomNotFound
 	}
-	if room.Owner != userID {
+	if !rooms.HasPlayerPermission(room, userID, permission) {
 		return rooms.Room{}, rooms.ErrForbidden
 	}
 	return room, nil
 }
 
-func newPlayer(user users.User, index int) rooms.Player {
+// removePlayer returns the room without the player and drops the card of the player from the active game.
+func (r *Repository) removePlayer(room rooms.Room, userID string, now time.Time) rooms.Room {
+	room.Players = slices.DeleteFunc(slices.Clone(room.Players), func(player rooms.Player) bool {
+		return player.UserID == userID
+	})
+	if game, contains := r.getActiveGame(room); contains {
+		game.Cards = dropUserCard(game.Cards, userID)
+		r.games[game.ID] = game
+	}
+	r.updateActiveGameAutoReveal(room, now)
+	return room
+}
+
+// updateActiveGameAutoReveal applies the auto reveal to the active game after the players of the room are changed.
+func (r *Repository) updateActiveGameAutoReveal(room rooms.Room, now time.Time) {
+	if game, contains := r.getActiveGame(room); contains {
+		r.games[game.ID] = updateAutoReveal(room, game, now)
+	}
+}
+
+// getActiveGame returns the last game of the room if it is active.
+func (r *Repository) getActiveGame(room rooms.Room) (rooms.Game, bool) {
+	if len(room.Games) == 0 {
+		return rooms.Game{}, false
+	}
+	game, contains := r.games[room.Games[len(room.Games)-1]]
+	if !contains || game.Status != rooms.GameStatusActive {
+		return rooms.Game{}, false
+	}
+	return game, true
+}
+
+func newPlayer(user users.User, index int, role string) rooms.Player {
 	name := user.Name
 	if len(name) == 0 {
 		name = "Player " + strconv.Itoa(index+1)
 	}
 
-	color := playerColors[index%len(playerColors)]
-	return rooms.Player{UserID: user.ID, Name: name, Color: color}
+	color := user.Color
+	if len(color) == 0 {
+		color = playerColors[index%len(playerColors)]
+	}
+	return rooms.Player{UserID: user.ID, Name: name, Color: color, Avatar: user.Avatar, Role: role}
 }
 
-func (r *Repository) getRoomAndGame(userID string, gameID string) (rooms.Room, rooms.Game, error) {
+// updatePlayer applies the profile of the user to the player, the name and color given by the room
+// are kept if the user hasn't set them.
+func updatePlayer(player rooms.Player, user users.User) rooms.Player {
+	if len(user.Name) > 0 {
+		player.Name = user.Name
+	}
+	if len(user.Color) > 0 {
+		player.Color = user.Color
+	}
+	player.Avatar = user.Avatar
+	return player
+}
+
+func (r *Repository) getRoomAndGame(userID string, gameID string, permission rooms.Permission) (rooms.Room, rooms.Game, error) {
 	game, contains := r.games[gameID]
 	if !contains {
 		return rooms.Room{}, rooms.Game{}, rooms.ErrGameNotFound
@@ -309,13 +871,184 @@ func (r *Repository) getRoomAndGame(userID string, gameID string) (rooms.Room, r
 	if !contains {
 		return rooms.Room{}, rooms.Game{}, rooms.ErrGameNotFound
 	}
-	if room.Owner != userID {
+	if !rooms.HasPlayerPermission(room, userID, permission) {
 		return rooms.Room{}, rooms.Game{}, rooms.ErrForbidden
 	}
 	return room, game, nil
 }
 
-func (r *Repository) saveRoom(room rooms.Room) {
-	room.Commit = idutils.GenerateID()
-	r.rooms[room.ID] = room
+// commitGameEvent commits the change of the game and returns the changed game.
+func (r *Repository) commitGameEvent(event rooms.Event, game rooms.Game) (rooms.Game, error) {
+	event.RoomID = game.RoomID
+	event.GameID = game.ID
+	if _, err := r.commitEvent(event); err != nil {
+		return rooms.Game{}, err
+	}
+	return r.games[game.ID], nil
+}
+
+// commitEvent stamps the event with the next sequence number of the room and a new commit,
+// writes it through the storage and applies it to the repository state.
+func (r *Repository) commitEvent(event rooms.Event) (rooms.Room, error) {
+	event.Sequence = r.rooms[event.RoomID].Sequence + 1
+	event.CreatedAt = time.Now()
+	event.Commit = idutils.GenerateID()
+	if err := r.storage.AppendEvent(event); err != nil {
+		return rooms.Room{}, err
+	}
+
+	r.applyEvent(event)
+	if len(r.events[event.RoomID]) > eventsLimit {
+		if err := r.compactEvents(event.RoomID); err != nil {
+			log.Println(fmt.Errorf("failed to compact room events: %w", err))
+		}
+	}
+	return r.rooms[event.RoomID], nil
+}
+
+// compactEvents replaces the oldest events of the room by an event with the room and the games they produced,
+// the state of the room isn't changed.
+func (r *Repository) compactEvents(roomID string) error {
+	events := r.events[roomID]
+	cut := len(events) - eventsKept
+	replay := NewRepo()
+	for _, event := range events[:cut] {
+		replay.applyEvent(event)
+	}
+	room, contains := replay.rooms[roomID]
+	if !contains {
+		return nil
+	}
+	games := make([]rooms.Game, 0, len(room.Games))
+	for _, gameID := range room.Games {
+		games = append(games, replay.games[gameID])
+	}
+	last := events[cut-1]
+	compacted := rooms.Event{
+		RoomID:    roomID,
+		Sequence:  last.Sequence,
+		Type:      rooms.EventRoomCompacted,
+		CreatedAt: last.CreatedAt,
+		Commit:    last.Commit,
+		Room:      &room,
+		Games:     games,
+	}
+	result := append([]rooms.Event{compacted}, events[cut:]...)
+	if err := r.storage.ReplaceEvents(roomID, result); err != nil {
+		return err
+	}
+	r.events[roomID] = result
+	return nil
+}
+
+func (r *Repository) applyEvent(event rooms.Event) {
+	if event.Type == rooms.EventRoomDeleted {
+		for _, gameID := range r.rooms[event.RoomID].Games {
+			delete(r.games, gameID)
+		}
+		delete(r.rooms, event.RoomID)
+		delete(r.events, event.RoomID)
+		r.notifyRoomChanged(event.RoomID)
+		return
+	}
+
+	room := r.rooms[event.RoomID]
+	if event.Room != nil || event.Games != nil {
+		// checkpoints and the events stored before the payloads were introduced carry the resulting room and games
+		if event.Room != nil {
+			room = withPlayerRoles(*event.Room)
+		}
+		for _, game := range event.Games {
+			r.games[game.ID] = game
+		}
+	} else {
+		room = r.applyChange(room, event)
+	}
+	room.Commit = event.Commit
+	room.Sequence = event.Sequence
+	r.rooms[event.RoomID] = room
+
+	r.events[event.RoomID] = append(r.events[event.RoomID], event)
+	r.notifyRoomChanged(event.RoomID)
+}
+
+// applyChange applies the payload of the event to the room and its games by the type of the event,
+// the changed games are stored and the changed room is returned.
+func (r *Repository) applyChange(room rooms.Room, event rooms.Event) rooms.Room {
+	switch event.Type {
+	case rooms.EventPlayerJoined:
+		room.Players = append(slices.Clone(room.Players), *event.Player)
+		room.VisitorsCount = room.VisitorsCount + 1
+		if event.InviteCode != nil {
+			room.InviteCodes = useInviteCode(room.InviteCodes, event.InviteCode.Code)
+		}
+		r.updateActiveGameAutoReveal(room, event.CreatedAt)
+	case rooms.EventPlayerLeft:
+		room = r.removePlayer(room, event.UserID, event.CreatedAt)
+	case rooms.EventPlayerKicked, rooms.EventPlayerRemoved, rooms.EventPlayerErased:
+		room = r.removePlayer(room, event.PlayerID, event.CreatedAt)
+		if event.OwnerID != "" {
+			room = transferOwnership(room, event.OwnerID)
+		}
+	case rooms.EventPlayerUpdated:
+		room.Players = replacePlayer(room.Players, *event.Player)
+		for _, gameID := range room.Games {
+			game, contains := r.games[gameID]
+			if contains && updateUserCards(&game, event.Player.UserID, *event.Player) {
+				r.games[gameID] = game
+			}
+		}
+	case rooms.EventPlayerRoleChanged:
+		room.Players = replacePlayer(room.Players, *event.Player)
+		if game, contains := r.getActiveGame(room); contains && !rooms.HasPermission(event.Player.Role, rooms.PermissionVote) {
+			game.Cards = dropUserCard(game.Cards, event.Player.UserID)
+			r.games[game.ID] = game
+		}
+		r.updateActiveGameAutoReveal(room, event.CreatedAt)
+	case rooms.EventOwnerChanged:
+		room = transferOwnership(room, event.PlayerID)
+	case rooms.EventInviteCodeCreated:
+		room.InviteCodes = append(removeInvalidInviteCodes(room.InviteCodes, event.CreatedAt), *event.InviteCode)
+	case rooms.EventInviteCodeRevoked:
+		room.InviteCodes = slices.DeleteFunc(slices.Clone(room.InviteCodes), func(code rooms.InviteCode) bool {
+			return code.Code == event.InviteCode.Code
+		})
+	case rooms.EventGameAdded:
+		room.Games = append(slices.Clone(room.Games), event.Game.ID)
+		r.games[event.Game.ID] = *event.Game
+	default:
+		if game, contains := r.games[event.GameID]; contains {
+			r.games[game.ID] = applyGameChange(room, game, event)
+		}
+	}
+	return room
+}
+
+// replaceEvents rewrites the history of the room and replays it, the room is deleted if there are no events.
+func (r *Repository) replaceEvents(roomID string, events []rooms.Event) error {
+	if err := r.storage.ReplaceEvents(roomID, events); err != nil {
+		return err
+	}
+
+	for _, gameID := range r.rooms[roomID].Games {
+		delete(r.games, gameID)
+	}
+	delete(r.rooms, roomID)
+	delete(r.events, roomID)
+	for _, event := range events {
+		r.applyEvent(event)
+	}
+	r.notifyRoomChanged(roomID)
+	return nil
+}
+
+func (r *Repository) notifyRoomChanged(roomID string) {
+	if ch, contains := r.changes[roomID]; contains {
+		close(ch)
+	}
+	if _, contains := r.rooms[roomID]; contains {
+		r.changes[roomID] = make(chan struct{})
+	} else {
+		delete(r.changes, roomID)
+	}
 }
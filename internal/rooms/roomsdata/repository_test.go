@@ -0,0 +1,341 @@
+package roomsdata
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+)
+
+// eventsStorage keeps the events in memory like a persistent storage.
+type eventsStorage struct {
+	events []rooms.Event
+}
+
+func (s *eventsStorage) LoadEvents() ([]rooms.Event, error) {
+	return s.events, nil
+}
+
+func (s *eventsStorage) AppendEvent(event rooms.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *eventsStorage) ReplaceEvents(roomID string, events []rooms.Event) error {
+	kept := make([]rooms.Event, 0, len(s.events))
+	for _, event := range s.events {
+		if event.RoomID != roomID {
+			kept = append(kept, event)
+		}
+	}
+	s.events = append(kept, events...)
+	return nil
+}
+
+func TestCompactEvents(t *testing.T) {
+	storage := &eventsStorage{}
+	repo, err := NewPersistentRepo(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := users.User{ID: "owner", Name: "Owner"}
+	room, err := repo.Create(owner, "Room", false, rooms.DefaultDeck(), rooms.AutoReveal{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	game, err := repo.AddGame(owner.ID, room.ID, rooms.Game{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < eventsLimit+10; i++ {
+		value := "1"
+		if i%2 == 0 {
+			value = "2"
+		}
+		if _, err := repo.SendCard(owner.ID, game.ID, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := repo.events[room.ID]
+	if len(events) > eventsLimit {
+		t.Errorf("len(events) = %d, want at most %d", len(events), eventsLimit)
+	}
+	if events[0].Type != rooms.EventRoomCompacted {
+		t.Errorf("events[0].Type = %q, want %q", events[0].Type, rooms.EventRoomCompacted)
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Sequence != events[i-1].Sequence+1 {
+			t.Fatalf("events[%d].Sequence = %d, want %d", i, events[i].Sequence, events[i-1].Sequence+1)
+		}
+	}
+
+	restored, err := NewPersistentRepo(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored.rooms, repo.rooms) {
+		t.Errorf("restored rooms = %v, want %v", restored.rooms, repo.rooms)
+	}
+	if !reflect.DeepEqual(restored.games, repo.games) {
+		t.Errorf("restored games = %v, want %v", restored.games, repo.games)
+	}
+}
+
+// jsonEventsStorage keeps the events encoded like a persistent storage.
+type jsonEventsStorage struct {
+	eventsStorage
+}
+
+func (s *jsonEventsStorage) LoadEvents() ([]rooms.Event, error) {
+	data, err := json.Marshal(s.events)
+	if err != nil {
+		return nil, err
+	}
+	var events []rooms.Event
+	err = json.Unmarshal(data, &events)
+	return events, err
+}
+
+func assertSameState(t *testing.T, got *Repository, want *Repository) {
+	t.Helper()
+	// the state is compared encoded, since decoded times lose their monotonic clock readings
+	for _, state := range []struct {
+		name      string
+		got, want any
+	}{
+		{name: "rooms", got: got.rooms, want: want.rooms},
+		{name: "games", got: got.games, want: want.games},
+	} {
+		gotData, err := json.Marshal(state.got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantData, err := json.Marshal(state.want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(gotData) != string(wantData) {
+			t.Errorf("restored %s = %s, want %s", state.name, gotData, wantData)
+		}
+	}
+}
+
+func TestReplayEvents(t *testing.T) {
+	storage := &jsonEventsStorage{}
+	repo, err := NewPersistentRepo(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := users.User{ID: "owner", Name: "Owner"}
+	voter := users.User{ID: "voter", Name: "Voter"}
+	guest := users.User{ID: "guest", Name: "Guest"}
+	room, err := repo.Create(owner, "Room", true, rooms.DefaultDeck(), rooms.AutoReveal{Enabled: true, Delay: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := repo.CreateInviteCode(owner.ID, room.ID, time.Time{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []func() error{
+		func() error { _, err := repo.Join(voter, room.ID, code.Code, ""); return err },
+		func() error { _, err := repo.Join(guest, room.ID, code.Code, rooms.RoleVoter); return err },
+		func() error {
+			_, err := repo.AddGame(owner.ID, room.ID, rooms.Game{Name: "First", Status: rooms.GameStatusActive}, nil)
+			return err
+		},
+		func() error { _, err := repo.SendCard(owner.ID, repo.rooms[room.ID].Games[0], "5"); return err },
+		func() error { _, err := repo.SendCard(voter.ID, repo.rooms[room.ID].Games[0], "?"); return err },
+		func() error {
+			_, err := repo.StartTimer(owner.ID, repo.rooms[room.ID].Games[0], time.Minute)
+			return err
+		},
+		func() error {
+			_, err := repo.ExtendTimer(owner.ID, repo.rooms[room.ID].Games[0], time.Minute)
+			return err
+		},
+		func() error { _, err := repo.SendCard(guest.ID, repo.rooms[room.ID].Games[0], "8"); return err },
+		func() error { _, err := repo.CompleteGame(owner.ID, repo.rooms[room.ID].Games[0]); return err },
+		func() error { _, err := repo.SetFinalEstimate(owner.ID, repo.rooms[room.ID].Games[0], "8"); return err },
+		func() error { _, err := repo.StartRound(owner.ID, repo.rooms[room.ID].Games[0]); return err },
+		func() error { _, err := repo.SendCard(voter.ID, repo.rooms[room.ID].Games[0], "3"); return err },
+		func() error {
+			_, err := repo.SetPlayerRole(owner.ID, room.ID, voter.ID, rooms.RoleObserver)
+			return err
+		},
+		func() error { voter.Name = "Renamed"; return repo.UpdatePlayer(voter) },
+		func() error { _, err := repo.TransferOwnership(owner.ID, room.ID, voter.ID); return err },
+		func() error { return repo.Kick(voter.ID, room.ID, guest.ID) },
+		func() error { return repo.RevokeInviteCode(voter.ID, room.ID, code.Code) },
+		func() error { _, err := repo.EraseUser(voter.ID); return err },
+		func() error { _, err := repo.ResetGame(owner.ID, repo.rooms[room.ID].Games[0]); return err },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+
+	for _, event := range storage.events {
+		isCheckpoint := event.Type == rooms.EventRoomCreated || event.Type == rooms.EventRoomCompacted
+		if !isCheckpoint && (event.Room != nil || event.Games != nil) {
+			t.Errorf("event %s carries the room state, want only its payload", event.Type)
+		}
+	}
+	restored, err := NewPersistentRepo(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameState(t, restored, repo)
+}
+
+func TestReplayLegacyEvents(t *testing.T) {
+	// events stored before the payloads were introduced have the field names as keys and carry the resulting state
+	data := `[
+		{"RoomID": "room", "Sequence": 1, "Type": "room_created", "CreatedAt": "2024-01-01T12:00:00Z", "Commit": "1", "UserID": "owner",
+			"Room": {"ID": "room", "Name": "Room", "Owner": "owner", "Players": [{"UserID": "owner", "Name": "Owner"}], "Games": []}},
+		{"RoomID": "room", "Sequence": 2, "Type": "game_added", "CreatedAt": "2024-01-01T12:01:00Z", "Commit": "2", "UserID": "owner",
+			"Room": {"ID": "room", "Name": "Room", "Owner": "owner", "Players": [{"UserID": "owner", "Name": "Owner"}], "Games": ["game"]},
+			"Games": [{"ID": "game", "RoomID": "room", "Name": "Game", "Status": "active"}]},
+		{"RoomID": "room", "Sequence": 3, "Type": "card_sent", "CreatedAt": "2024-01-01T12:02:00Z", "Commit": "3", "UserID": "owner",
+			"Games": [{"ID": "game", "RoomID": "room", "Name": "Game", "Status": "active", "Cards": [{"Player": {"UserID": "owner"}, "Value": "5", "Score": 5}]}]}
+	]`
+	var events []rooms.Event
+	if err := json.Unmarshal([]byte(data), &events); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewPersistentRepo(&eventsStorage{events: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	room, contains := repo.rooms["room"]
+	if !contains || room.Sequence != 3 || room.Commit != "3" || room.Players[0].Role != rooms.RoleOwner {
+		t.Fatalf("room = %v, want the room of the last event with roles", room)
+	}
+	game := repo.games["game"]
+	if len(game.Cards) != 1 || game.Cards[0].Value != "5" {
+		t.Errorf("game cards = %v, want the sent card", game.Cards)
+	}
+	if events[2].UserID != "owner" || !events[2].CreatedAt.Equal(time.Date(2024, 1, 1, 12, 2, 0, 0, time.UTC)) {
+		t.Errorf("event user and time = %q, %v, want them decoded", events[2].UserID, events[2].CreatedAt)
+	}
+}
+
+func TestUpdatePlayerSkipsUnchangedRooms(t *testing.T) {
+	repo := NewRepo()
+	owner := users.User{ID: "owner", Name: "Owner"}
+	room, err := repo.Create(owner, "Room", false, rooms.DefaultDeck(), rooms.AutoReveal{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	game, err := repo.AddGame(owner.ID, room.ID, rooms.Game{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SendCard(owner.ID, game.ID, "5"); err != nil {
+		t.Fatal(err)
+	}
+
+	sequence := repo.rooms[room.ID].Sequence
+	if err := repo.UpdatePlayer(owner); err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.rooms[room.ID].Sequence; got != sequence {
+		t.Errorf("sequence after an unchanged profile = %d, want %d", got, sequence)
+	}
+
+	owner.Name = "Renamed"
+	if err := repo.UpdatePlayer(owner); err != nil {
+		t.Fatal(err)
+	}
+	events := repo.events[room.ID]
+	event := events[len(events)-1]
+	if event.Type != rooms.EventPlayerUpdated || event.Player == nil || event.Player.Name != "Renamed" {
+		t.Fatalf("last event = %s with player %v, want %s with the renamed player", event.Type, event.Player, rooms.EventPlayerUpdated)
+	}
+	if name := repo.rooms[room.ID].Players[0].Name; name != "Renamed" {
+		t.Errorf("player name = %q, want %q", name, "Renamed")
+	}
+	if name := repo.games[game.ID].Cards[0].Player.Name; name != "Renamed" {
+		t.Errorf("card player name = %q, want %q", name, "Renamed")
+	}
+}
+
+func TestStartRoundOnlyForLastGame(t *testing.T) {
+	repo := NewRepo()
+	owner := users.User{ID: "owner", Name: "Owner"}
+	room, err := repo.Create(owner, "Room", false, rooms.DefaultDeck(), rooms.AutoReveal{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := repo.AddGame(owner.ID, room.ID, rooms.Game{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CompleteGame(owner.ID, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	last, err := repo.AddGame(owner.ID, room.ID, rooms.Game{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CompleteGame(owner.ID, last.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.StartRound(owner.ID, first.ID); !errors.Is(err, rooms.ErrIllegalGameStatus) {
+		t.Errorf("StartRound() of an earlier game error = %v, want %v", err, rooms.ErrIllegalGameStatus)
+	}
+	game, err := repo.StartRound(owner.ID, last.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Status != rooms.GameStatusActive || len(game.Rounds) != 1 {
+		t.Errorf("StartRound() = %s with %d rounds, want active with 1 round", game.Status, len(game.Rounds))
+	}
+}
+
+func TestReopeningGameClearsFinalEstimate(t *testing.T) {
+	reopen := map[string]func(repo *Repository, userID string, gameID string) (rooms.Game, error){
+		"reset":     (*Repository).ResetGame,
+		"new round": (*Repository).StartRound,
+	}
+	for name, reopenGame := range reopen {
+		t.Run(name, func(t *testing.T) {
+			repo := NewRepo()
+			owner := users.User{ID: "owner", Name: "Owner"}
+			room, err := repo.Create(owner, "Room", false, rooms.DefaultDeck(), rooms.AutoReveal{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			game, err := repo.AddGame(owner.ID, room.ID, rooms.Game{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.CompleteGame(owner.ID, game.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.SetFinalEstimate(owner.ID, game.ID, "8"); err != nil {
+				t.Fatal(err)
+			}
+
+			game, err = reopenGame(repo, owner.ID, game.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if game.Status != rooms.GameStatusActive || game.FinalEstimate != "" {
+				t.Errorf("reopened game = %s with final estimate %q, want active without it", game.Status, game.FinalEstimate)
+			}
+			changes := game.FinalEstimateChanges
+			if len(changes) != 2 || changes[0].Value != "8" || changes[1].Value != "" || changes[1].UserID != owner.ID {
+				t.Errorf("FinalEstimateChanges = %v, want the estimate and its clearing", changes)
+			}
+		})
+	}
+}
@@ -1,12 +1,21 @@
 package roomsdata
 
 import (
+	cryptorand "crypto/rand"
+	"log"
+	"math/big"
 	"math/rand"
+	"slices"
+	"time"
 
 	"aleksandersh.github.io/planning-poker-server/internal/rooms"
 )
 
-const letterBytes = "abcdefghijklmnopqrstuvwxyz"
+const (
+	letterBytes = "abcdefghijklmnopqrstuvwxyz"
+	// inviteCodeBytes excludes characters which are easy to confuse.
+	inviteCodeBytes = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
 
 func generateRoomID() string {
 	b := make([]byte, 10)
@@ -25,15 +34,71 @@ func isPlayerExists(room rooms.Room, userID string) bool {
 	return false
 }
 
-func isInviteCodeAccepted(room rooms.Room, inviteCode string) bool {
-	if room.InviteCodeRequired {
-		for _, code := range room.InviteCodes {
-			if code.Code == inviteCode {
-				return true
-			}
+func generateInviteCode() string {
+	b := make([]byte, 8)
+	limit := big.NewInt(int64(len(inviteCodeBytes)))
+	for i := range b {
+		n, err := cryptorand.Int(cryptorand.Reader, limit)
+		if err != nil {
+			log.Panicf("failed to generate invite code: %v", err)
 		}
+		b[i] = inviteCodeBytes[n.Int64()]
 	}
-	return false
+	return string(b)
+}
+
+// findInviteCode returns the index of the invite code if it can be used at the given time.
+func findInviteCode(room rooms.Room, inviteCode string, now time.Time) int {
+	for i, code := range room.InviteCodes {
+		if code.Code == inviteCode && isInviteCodeValid(code, now) {
+			return i
+		}
+	}
+	return rooms.UnknownIndex
+}
+
+func isInviteCodeValid(code rooms.InviteCode, now time.Time) bool {
+	if !code.ExpiresAt.IsZero() && !now.Before(code.ExpiresAt) {
+		return false
+	}
+	return code.MaxUses == 0 || code.Uses < code.MaxUses
+}
+
+// removeInvalidInviteCodes returns a copy of the invite codes without the expired and used up ones.
+func removeInvalidInviteCodes(codes []rooms.InviteCode, now time.Time) []rooms.InviteCode {
+	return slices.DeleteFunc(slices.Clone(codes), func(code rooms.InviteCode) bool {
+		return !isInviteCodeValid(code, now)
+	})
+}
+
+// useInviteCode returns a copy of the invite codes with the use of the code counted.
+func useInviteCode(codes []rooms.InviteCode, inviteCode string) []rooms.InviteCode {
+	codes = slices.Clone(codes)
+	for i, code := range codes {
+		if code.Code == inviteCode {
+			codes[i].Uses = code.Uses + 1
+		}
+	}
+	return codes
+}
+
+// replacePlayer returns a copy of the players with the player of the same user replaced.
+func replacePlayer(players []rooms.Player, player rooms.Player) []rooms.Player {
+	players = slices.Clone(players)
+	if idx := findPlayer(players, player.UserID); idx != rooms.UnknownIndex {
+		players[idx] = player
+	}
+	return players
+}
+
+// findPlayer returns the index of the player of the user.
+func findPlayer(players []rooms.Player, userID string) int {
+	for i, player := range players {
+		if player.UserID == userID {
+			return i
+		}
+	}
+	return rooms.UnknownIndex
 }
 
 func getPlayer(room rooms.Room, userID string) (rooms.Player, error) {
@@ -44,3 +109,55 @@ func getPlayer(room rooms.Room, userID string) (rooms.Player, error) {
 	}
 	return rooms.Player{}, rooms.ErrForbidden
 }
+
+// withPlayerRoles gives roles to players of rooms created before roles were introduced,
+// the owner gets the owner role and other players get the voter role.
+func withPlayerRoles(room rooms.Room) rooms.Room {
+	if !slices.ContainsFunc(room.Players, func(player rooms.Player) bool { return player.Role == "" }) {
+		return room
+	}
+	room.Players = slices.Clone(room.Players)
+	for i, player := range room.Players {
+		if player.Role != "" {
+			continue
+		}
+		if player.UserID == room.Owner {
+			room.Players[i].Role = rooms.RoleOwner
+		} else {
+			room.Players[i].Role = rooms.RoleVoter
+		}
+	}
+	return room
+}
+
+// findNextOwner returns the player taking over the room from the owner, moderators are preferred.
+// The result is empty if the owner is the only player.
+func findNextOwner(room rooms.Room) string {
+	nextOwner := ""
+	for _, player := range room.Players {
+		if player.UserID == room.Owner {
+			continue
+		}
+		if player.Role == rooms.RoleModerator {
+			return player.UserID
+		}
+		if nextOwner == "" {
+			nextOwner = player.UserID
+		}
+	}
+	return nextOwner
+}
+
+// transferOwnership gives the owner role to the player, the previous owner becomes a moderator.
+func transferOwnership(room rooms.Room, playerID string) rooms.Room {
+	room.Players = slices.Clone(room.Players)
+	for i, player := range room.Players {
+		if player.UserID == playerID {
+			room.Players[i].Role = rooms.RoleOwner
+		} else if player.Role == rooms.RoleOwner {
+			room.Players[i].Role = rooms.RoleModerator
+		}
+	}
+	room.Owner = playerID
+	return room
+}
@@ -0,0 +1,30 @@
+package roomsdata
+
+import (
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+)
+
+// Storage keeps the append-only log of room events. The repository keeps the state derived
+// from the events in memory and writes every new event through the storage before applying it.
+type Storage interface {
+	// LoadEvents returns all events in the order they were appended.
+	LoadEvents() ([]rooms.Event, error)
+	AppendEvent(event rooms.Event) error
+	// ReplaceEvents replaces all events of the room, the history is rewritten to erase personal data
+	// and to compact a long log into a checkpoint.
+	ReplaceEvents(roomID string, events []rooms.Event) error
+}
+
+type memoryStorage struct{}
+
+func (memoryStorage) LoadEvents() ([]rooms.Event, error) {
+	return nil, nil
+}
+
+func (memoryStorage) AppendEvent(event rooms.Event) error {
+	return nil
+}
+
+func (memoryStorage) ReplaceEvents(roomID string, events []rooms.Event) error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package roomsdomain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// GamesScheduler completes games on time, when the auto reveal delay or the voting timer expires.
+type GamesScheduler struct {
+	roomsRepository Repository
+	interval        time.Duration
+}
+
+func NewGamesScheduler(roomsRepository Repository, interval time.Duration) *GamesScheduler {
+	return &GamesScheduler{roomsRepository: roomsRepository, interval: interval}
+}
+
+// Run checks the games on the interval until the context is done.
+func (s *GamesScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.roomsRepository.CompleteDueGames(now); err != nil {
+				log.Println(fmt.Errorf("failed to complete due games: %w", err))
+			}
+		}
+	}
+}
@@ -1,30 +1,33 @@
 package roomsdomain
 
 import (
+	"time"
+
 	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydata"
 	"aleksandersh.github.io/planning-poker-server/internal/rooms"
-	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdata"
 )
 
 type GamesService struct {
-	roomsRepository    *roomsdata.Repository
+	roomsRepository    Repository
 	activityRepository *activitydata.Repository
 }
 
-func NewGamesService(roomsRepository *roomsdata.Repository, activityRepository *activitydata.Repository) *GamesService {
+func NewGamesService(roomsRepository Repository, activityRepository *activitydata.Repository) *GamesService {
 	return &GamesService{roomsRepository: roomsRepository, activityRepository: activityRepository}
 }
 
-func (s *GamesService) Create(userID string, roomID string, name string) (rooms.Game, error) {
+// Create adds a game to the room, the deck and the auto reveal of the room are used if they aren't given.
+func (s *GamesService) Create(userID string, roomID string, name string, deck rooms.Deck, autoReveal *rooms.AutoReveal) (rooms.Game, error) {
 	game := rooms.Game{
 		RoomID:       roomID,
 		Name:         name,
 		Status:       rooms.GameStatusActive,
+		Deck:         deck,
 		MaxScore:     0,
 		AverageScore: 0,
 		Cards:        []rooms.Card{},
 	}
-	game, err := s.roomsRepository.AddGame(userID, roomID, game)
+	game, err := s.roomsRepository.AddGame(userID, roomID, game, autoReveal)
 	if err == nil {
 		s.activityRepository.AddPlayerActivity(game.RoomID, userID)
 	}
@@ -47,8 +50,8 @@ func (s *GamesService) Reset(userID string, gameID string) (rooms.Game, error) {
 	return game, err
 }
 
-func (s *GamesService) SendCard(userID string, gameID string, score int) (rooms.Game, error) {
-	game, err := s.roomsRepository.SendCard(userID, gameID, score)
+func (s *GamesService) SendCard(userID string, gameID string, value string) (rooms.Game, error) {
+	game, err := s.roomsRepository.SendCard(userID, gameID, value)
 	if err == nil {
 		s.activityRepository.AddPlayerActivity(game.RoomID, userID)
 	}
@@ -62,3 +65,52 @@ func (s *GamesService) DropCard(userID string, gameID string) (rooms.Game, error
 	}
 	return game, err
 }
+
+// StartRound archives the current round of the completed game, so players vote again after a discussion.
+func (s *GamesService) StartRound(userID string, gameID string) (rooms.Game, error) {
+	game, err := s.roomsRepository.StartRound(userID, gameID)
+	if err == nil {
+		s.activityRepository.AddPlayerActivity(game.RoomID, userID)
+	}
+	return game, err
+}
+
+// SetFinalEstimate chooses the estimate the team commits to, it is kept apart from the computed statistics.
+func (s *GamesService) SetFinalEstimate(userID string, gameID string, value string) (rooms.Game, error) {
+	game, err := s.roomsRepository.SetFinalEstimate(userID, gameID, value)
+	if err == nil {
+		s.activityRepository.AddPlayerActivity(game.RoomID, userID)
+	}
+	return game, err
+}
+
+// StartTimer starts the voting timer, the game is completed when it expires.
+func (s *GamesService) StartTimer(userID string, gameID string, duration time.Duration) (rooms.Game, error) {
+	if err := rooms.ValidateTimerDuration(duration); err != nil {
+		return rooms.Game{}, err
+	}
+	game, err := s.roomsRepository.StartTimer(userID, gameID, duration)
+	if err == nil {
+		s.activityRepository.AddPlayerActivity(game.RoomID, userID)
+	}
+	return game, err
+}
+
+func (s *GamesService) ExtendTimer(userID string, gameID string, duration time.Duration) (rooms.Game, error) {
+	if err := rooms.ValidateTimerDuration(duration); err != nil {
+		return rooms.Game{}, err
+	}
+	game, err := s.roomsRepository.ExtendTimer(userID, gameID, duration)
+	if err == nil {
+		s.activityRepository.AddPlayerActivity(game.RoomID, userID)
+	}
+	return game, err
+}
+
+func (s *GamesService) CancelTimer(userID string, gameID string) (rooms.Game, error) {
+	game, err := s.roomsRepository.CancelTimer(userID, gameID)
+	if err == nil {
+		s.activityRepository.AddPlayerActivity(game.RoomID, userID)
+	}
+	return game, err
+}
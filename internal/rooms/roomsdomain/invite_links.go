@@ -0,0 +1,68 @@
+package roomsdomain
+
+import (
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+)
+
+// inviteLinkClaims are signed into invite link tokens, so joining by a link doesn't need a lookup.
+type inviteLinkClaims struct {
+	RoomID    string `json:"room"`
+	Role      string `json:"role,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// CreateInviteLink returns a signed token which lets users join the room with the role until it expires.
+// The voter role is given if the role is empty, only the owner can invite moderators.
+func (rs *RoomsService) CreateInviteLink(userID string, roomID string, ttl time.Duration, role string) (rooms.InviteLink, error) {
+	if role != "" && (role == rooms.RoleOwner || !rooms.IsKnownRole(role)) {
+		return rooms.InviteLink{}, rooms.ErrUnknownRole
+	}
+
+	room, err := rs.roomsRepository.Get(userID, roomID)
+	if err != nil {
+		return rooms.InviteLink{}, err
+	}
+	permission := rooms.PermissionManagePlayers
+	if role == rooms.RoleModerator {
+		permission = rooms.PermissionManageRoom
+	}
+	if !rooms.HasPlayerPermission(room, userID, permission) {
+		return rooms.InviteLink{}, rooms.ErrForbidden
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	token, err := rs.linkSigner.Sign(inviteLinkClaims{RoomID: roomID, Role: role, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return rooms.InviteLink{}, err
+	}
+
+	rs.activityRepository.AddPlayerActivity(roomID, userID)
+	return rooms.InviteLink{Token: token, Role: role, ExpiresAt: expiresAt}, nil
+}
+
+// JoinByLink adds the user to the room if the invite link token is valid for the room.
+// The role of the link is given to the user, the user chooses the role if the link has none.
+func (rs *RoomsService) JoinByLink(user users.User, roomID string, token string, role string) (rooms.Room, error) {
+	var claims inviteLinkClaims
+	if err := rs.linkSigner.Verify(token, &claims); err != nil {
+		return rooms.Room{}, rooms.ErrForbidden
+	}
+	if claims.RoomID != roomID || !time.Now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return rooms.Room{}, rooms.ErrForbidden
+	}
+
+	if claims.Role != "" {
+		role = claims.Role
+	} else if role != "" && !rooms.IsSelectableRole(role) {
+		return rooms.Room{}, rooms.ErrUnknownRole
+	}
+
+	room, err := rs.roomsRepository.JoinInvited(user, roomID, role)
+	if err == nil {
+		rs.activityRepository.AddPlayerActivity(roomID, user.ID)
+	}
+	return room, err
+}
@@ -0,0 +1,93 @@
+package roomsdomain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydata"
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdata"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/signutils"
+)
+
+func newTestRoomsService(t *testing.T) (*RoomsService, rooms.Room) {
+	t.Helper()
+	service := NewRoomsService(roomsdata.NewRepo(), activitydata.NewRepository(), signutils.NewSigner(signutils.GenerateKey()))
+	room, err := service.Create(users.User{ID: "owner"}, "Room", true, rooms.DefaultDeck(), rooms.AutoReveal{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service, room
+}
+
+func findRole(room rooms.Room, userID string) string {
+	for _, player := range room.Players {
+		if player.UserID == userID {
+			return player.Role
+		}
+	}
+	return ""
+}
+
+func TestInviteLinkRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		linkRole string
+		joinRole string
+		want     string
+	}{
+		{name: "voter by default", want: rooms.RoleVoter},
+		{name: "role chosen by the user", joinRole: rooms.RoleObserver, want: rooms.RoleObserver},
+		{name: "role of the link", linkRole: rooms.RoleObserver, want: rooms.RoleObserver},
+		{name: "role of the link takes precedence", linkRole: rooms.RoleModerator, joinRole: rooms.RoleObserver, want: rooms.RoleModerator},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, room := newTestRoomsService(t)
+			link, err := service.CreateInviteLink("owner", room.ID, time.Hour, test.linkRole)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if link.Role != test.linkRole {
+				t.Errorf("link.Role = %q, want %q", link.Role, test.linkRole)
+			}
+
+			joined, err := service.JoinByLink(users.User{ID: "guest"}, room.ID, link.Token, test.joinRole)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if role := findRole(joined, "guest"); role != test.want {
+				t.Errorf("role = %q, want %q", role, test.want)
+			}
+		})
+	}
+}
+
+func TestInviteLinkRoleValidation(t *testing.T) {
+	service, room := newTestRoomsService(t)
+	for _, role := range []string{rooms.RoleOwner, "admin"} {
+		if _, err := service.CreateInviteLink("owner", room.ID, time.Hour, role); !errors.Is(err, rooms.ErrUnknownRole) {
+			t.Errorf("CreateInviteLink(%q) error = %v, want %v", role, err, rooms.ErrUnknownRole)
+		}
+	}
+
+	link, err := service.CreateInviteLink("owner", room.ID, time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, role := range []string{rooms.RoleOwner, rooms.RoleModerator, "admin"} {
+		if _, err := service.JoinByLink(users.User{ID: "guest"}, room.ID, link.Token, role); !errors.Is(err, rooms.ErrUnknownRole) {
+			t.Errorf("JoinByLink(%q) error = %v, want %v", role, err, rooms.ErrUnknownRole)
+		}
+	}
+
+	moderatorLink, err := service.CreateInviteLink("owner", room.ID, time.Hour, rooms.RoleModerator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.JoinByLink(users.User{ID: "guest"}, "other", moderatorLink.Token, ""); !errors.Is(err, rooms.ErrForbidden) {
+		t.Errorf("JoinByLink() to other room error = %v, want %v", err, rooms.ErrForbidden)
+	}
+}
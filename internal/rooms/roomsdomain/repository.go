@@ -0,0 +1,45 @@
+package roomsdomain
+
+import (
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+)
+
+// Repository stores rooms and their games, access to them is checked by the user ID.
+type Repository interface {
+	Create(user users.User, name string, inviteCodeRequired bool, deck rooms.Deck, autoReveal rooms.AutoReveal) (rooms.Room, error)
+	Get(userID string, roomID string) (rooms.Room, error)
+	Delete(userID string, roomID string) error
+	// Join adds the user with the voter or observer role, the voter role is given if the role is empty.
+	Join(user users.User, roomID string, inviteCode string, role string) (rooms.Room, error)
+	// JoinInvited adds the user with the role, the voter role is given if the role is empty.
+	JoinInvited(user users.User, roomID string, role string) (rooms.Room, error)
+	Leave(userID string, roomID string) error
+	Kick(userID string, roomID string, playerID string) error
+	TransferOwnership(userID string, roomID string, playerID string) (rooms.Room, error)
+	SetPlayerRole(userID string, roomID string, playerID string, role string) (rooms.Room, error)
+	CreateInviteCode(userID string, roomID string, expiresAt time.Time, maxUses int) (rooms.InviteCode, error)
+	GetInviteCodes(userID string, roomID string) ([]rooms.InviteCode, error)
+	RevokeInviteCode(userID string, roomID string, inviteCode string) error
+	GetRoomState(userID string, roomID string) (rooms.RoomState, error)
+	// ObserveRoomState returns the room state and a channel closed on the next change of the room.
+	ObserveRoomState(userID string, roomID string) (rooms.RoomState, <-chan struct{}, error)
+	// GetEvents returns the events of the room with sequence numbers greater than the given one.
+	GetEvents(userID string, roomID string, afterSequence int) ([]rooms.Event, error)
+
+	// AddGame adds the game, the deck and the auto reveal of the room are used if they aren't given.
+	AddGame(userID string, roomID string, game rooms.Game, autoReveal *rooms.AutoReveal) (rooms.Game, error)
+	CompleteGame(userID string, gameID string) (rooms.Game, error)
+	ResetGame(userID string, gameID string) (rooms.Game, error)
+	SendCard(userID string, gameID string, value string) (rooms.Game, error)
+	DropCard(userID string, gameID string) (rooms.Game, error)
+	StartRound(userID string, gameID string) (rooms.Game, error)
+	SetFinalEstimate(userID string, gameID string, value string) (rooms.Game, error)
+	StartTimer(userID string, gameID string, duration time.Duration) (rooms.Game, error)
+	ExtendTimer(userID string, gameID string, duration time.Duration) (rooms.Game, error)
+	CancelTimer(userID string, gameID string) (rooms.Game, error)
+	// CompleteDueGames completes the games whose pending auto reveal or voting timer is due by the time.
+	CompleteDueGames(now time.Time) error
+}
@@ -1,30 +1,30 @@
 package roomsdomain
 
 import (
+	"context"
+	"time"
+
 	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydata"
 	"aleksandersh.github.io/planning-poker-server/internal/rooms"
-	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdata"
 	"aleksandersh.github.io/planning-poker-server/internal/users"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/signutils"
 )
 
 type RoomsService struct {
-	roomsRepository    *roomsdata.Repository
+	roomsRepository    Repository
 	activityRepository *activitydata.Repository
+	linkSigner         *signutils.Signer
 }
 
-func NewRoomsService(roomsRepository *roomsdata.Repository, activityRepository *activitydata.Repository) *RoomsService {
-	return &RoomsService{roomsRepository: roomsRepository, activityRepository: activityRepository}
+func NewRoomsService(roomsRepository Repository, activityRepository *activitydata.Repository, linkSigner *signutils.Signer) *RoomsService {
+	return &RoomsService{roomsRepository: roomsRepository, activityRepository: activityRepository, linkSigner: linkSigner}
 }
 
-func (rs *RoomsService) Create(user users.User, name string, inviteCodeRequired bool) (rooms.Room, error) {
-	room, err := rs.roomsRepository.Create(user, name, inviteCodeRequired)
-	if err != nil {
-		return room, err
-	} else {
+func (rs *RoomsService) Create(user users.User, name string, inviteCodeRequired bool, deck rooms.Deck, autoReveal rooms.AutoReveal) (rooms.Room, error) {
+	room, err := rs.roomsRepository.Create(user, name, inviteCodeRequired, deck, autoReveal)
+	if err == nil {
 		rs.activityRepository.AddPlayerActivity(room.ID, user.ID)
 	}
-
-	// todo: start activity watcher
 	return room, err
 }
 
@@ -40,19 +40,77 @@ func (rs *RoomsService) Delete(userID string, roomID string) error {
 	err := rs.roomsRepository.Delete(userID, roomID)
 	if err == nil {
 		rs.activityRepository.AddUserActivity(userID)
-		// todo: stop room activity watcher
+		rs.activityRepository.DeleteRoomActivity(roomID)
 	}
 	return err
 }
 
-func (rs *RoomsService) Join(user users.User, roomID string, inviteCode string) (rooms.Room, error) {
-	room, err := rs.roomsRepository.Join(user, roomID, inviteCode)
+func (rs *RoomsService) Join(user users.User, roomID string, inviteCode string, role string) (rooms.Room, error) {
+	room, err := rs.roomsRepository.Join(user, roomID, inviteCode, role)
 	if err == nil {
 		rs.activityRepository.AddPlayerActivity(roomID, user.ID)
 	}
 	return room, err
 }
 
+func (rs *RoomsService) Leave(userID string, roomID string) error {
+	err := rs.roomsRepository.Leave(userID, roomID)
+	if err == nil {
+		rs.activityRepository.AddUserActivity(userID)
+		rs.activityRepository.DeleteActivity(userID, roomID)
+	}
+	return err
+}
+
+func (rs *RoomsService) Kick(userID string, roomID string, playerID string) error {
+	err := rs.roomsRepository.Kick(userID, roomID, playerID)
+	if err == nil {
+		rs.activityRepository.AddPlayerActivity(roomID, userID)
+		rs.activityRepository.DeleteActivity(playerID, roomID)
+	}
+	return err
+}
+
+func (rs *RoomsService) TransferOwnership(userID string, roomID string, playerID string) (rooms.Room, error) {
+	room, err := rs.roomsRepository.TransferOwnership(userID, roomID, playerID)
+	if err == nil {
+		rs.activityRepository.AddPlayerActivity(roomID, userID)
+	}
+	return room, err
+}
+
+func (rs *RoomsService) SetPlayerRole(userID string, roomID string, playerID string, role string) (rooms.Room, error) {
+	room, err := rs.roomsRepository.SetPlayerRole(userID, roomID, playerID, role)
+	if err == nil {
+		rs.activityRepository.AddPlayerActivity(roomID, userID)
+	}
+	return room, err
+}
+
+func (rs *RoomsService) CreateInviteCode(userID string, roomID string, expiresAt time.Time, maxUses int) (rooms.InviteCode, error) {
+	code, err := rs.roomsRepository.CreateInviteCode(userID, roomID, expiresAt, maxUses)
+	if err == nil {
+		rs.activityRepository.AddPlayerActivity(roomID, userID)
+	}
+	return code, err
+}
+
+func (rs *RoomsService) GetInviteCodes(userID string, roomID string) ([]rooms.InviteCode, error) {
+	codes, err := rs.roomsRepository.GetInviteCodes(userID, roomID)
+	if err == nil {
+		rs.activityRepository.AddPlayerActivity(roomID, userID)
+	}
+	return codes, err
+}
+
+func (rs *RoomsService) RevokeInviteCode(userID string, roomID string, inviteCode string) error {
+	err := rs.roomsRepository.RevokeInviteCode(userID, roomID, inviteCode)
+	if err == nil {
+		rs.activityRepository.AddPlayerActivity(roomID, userID)
+	}
+	return err
+}
+
 func (rs *RoomsService) GetState(userID string, roomID string) (rooms.RoomState, error) {
 	roomState, err := rs.roomsRepository.GetRoomState(userID, roomID)
 	if err == nil {
@@ -60,3 +118,50 @@ func (rs *RoomsService) GetState(userID string, roomID string) (rooms.RoomState,
 	}
 	return roomState, err
 }
+
+func (rs *RoomsService) ObserveState(userID string, roomID string) (rooms.RoomState, <-chan struct{}, error) {
+	roomState, changed, err := rs.roomsRepository.ObserveRoomState(userID, roomID)
+	if err == nil {
+		rs.activityRepository.AddPlayerActivity(roomID, userID)
+	}
+	return roomState, changed, err
+}
+
+// KeepAlive records the activity of the player who keeps the room state stream open without other requests.
+func (rs *RoomsService) KeepAlive(userID string, roomID string) {
+	rs.activityRepository.AddPlayerActivity(roomID, userID)
+}
+
+func (rs *RoomsService) GetEvents(userID string, roomID string, afterSequence int) ([]rooms.Event, error) {
+	events, err := rs.roomsRepository.GetEvents(userID, roomID, afterSequence)
+	if err == nil {
+		rs.activityRepository.AddPlayerActivity(roomID, userID)
+	}
+	return events, err
+}
+
+// WaitState returns the room state as soon as its commit differs from the given one,
+// the timeout elapses or the context is done, whichever happens first.
+func (rs *RoomsService) WaitState(ctx context.Context, userID string, roomID string, commit string, timeout time.Duration) (rooms.RoomState, error) {
+	roomState, changed, err := rs.ObserveState(userID, roomID)
+	if err != nil || roomState.Room.Commit != commit || timeout <= 0 {
+		return roomState, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for roomState.Room.Commit == commit {
+		select {
+		case <-ctx.Done():
+			return roomState, nil
+		case <-timer.C:
+			return roomState, nil
+		case <-changed:
+		}
+		roomState, changed, err = rs.roomsRepository.ObserveRoomState(userID, roomID)
+		if err != nil {
+			return roomState, err
+		}
+	}
+	return roomState, nil
+}
@@ -0,0 +1,144 @@
+package rooms
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Statistics describe the cards of a completed voting round, special cards are counted only by the histogram.
+type Statistics struct {
+	// Count is a number of scored cards.
+	Count             int
+	Min               float64
+	Max               float64
+	Average           float64
+	Median            float64
+	StandardDeviation float64
+	// Modes are the most frequent values of scored cards.
+	Modes []string
+	// Consensus is set if at least two scored cards are sent and all of them have the same value,
+	// special cards are treated as abstentions.
+	Consensus bool
+	// NearestCard is a value of the deck card nearest to the average, the higher card wins a tie.
+	// It is empty if there are no scored cards or the game has no deck.
+	NearestCard string
+	Histogram   []HistogramBar
+}
+
+// HistogramBar is a number of cards with the value.
+type HistogramBar struct {
+	Value string
+	Count int
+}
+
+func NewStatistics(cards []Card, deck Deck) Statistics {
+	histogram := newHistogram(cards, deck)
+	scores := make([]float64, 0, len(cards))
+	for _, card := range cards {
+		if card.Special == "" {
+			scores = append(scores, card.Score)
+		}
+	}
+	statistics := Statistics{Count: len(scores), Histogram: histogram}
+	if len(scores) == 0 {
+		return statistics
+	}
+
+	slices.Sort(scores)
+	statistics.Min = scores[0]
+	statistics.Max = scores[len(scores)-1]
+	sum := 0.0
+	for _, score := range scores {
+		sum += score
+	}
+	statistics.Average = sum / float64(len(scores))
+	if len(scores)%2 == 1 {
+		statistics.Median = scores[len(scores)/2]
+	} else {
+		statistics.Median = (scores[len(scores)/2-1] + scores[len(scores)/2]) / 2
+	}
+	variance := 0.0
+	for _, score := range scores {
+		variance += (score - statistics.Average) * (score - statistics.Average)
+	}
+	statistics.StandardDeviation = math.Sqrt(variance / float64(len(scores)))
+
+	statistics.Modes = findModes(histogram)
+	statistics.Consensus = statistics.Count >= 2 && statistics.Min == statistics.Max
+	statistics.NearestCard = findNearestCard(deck, statistics.Average)
+	return statistics
+}
+
+// newHistogram counts the cards by their values in the order of the deck, special cards go last.
+func newHistogram(cards []Card, deck Deck) []HistogramBar {
+	histogram := make([]HistogramBar, 0, len(cards))
+	for _, card := range cards {
+		value := card.Value
+		if len(value) == 0 {
+			value = FormatScore(card.Score)
+		}
+		idx := slices.IndexFunc(histogram, func(bar HistogramBar) bool { return bar.Value == value })
+		if idx == UnknownIndex {
+			histogram = append(histogram, HistogramBar{Value: value, Count: 1})
+		} else {
+			histogram[idx].Count++
+		}
+	}
+
+	order := func(value string) (int, float64) {
+		if _, special := specialCardValues[value]; special {
+			return len(deck.Cards) + 1, 0
+		}
+		idx := slices.IndexFunc(deck.Cards, func(card DeckCard) bool { return card.Value == value })
+		if idx == UnknownIndex {
+			score, _ := parseScore(value)
+			return len(deck.Cards), score
+		}
+		return idx, 0
+	}
+	slices.SortStableFunc(histogram, func(a HistogramBar, b HistogramBar) int {
+		aIdx, aScore := order(a.Value)
+		bIdx, bScore := order(b.Value)
+		if aIdx != bIdx {
+			return cmp.Compare(aIdx, bIdx)
+		}
+		return cmp.Compare(aScore, bScore)
+	})
+	return histogram
+}
+
+func findModes(histogram []HistogramBar) []string {
+	maxCount := 0
+	var modes []string
+	for _, bar := range histogram {
+		if _, special := specialCardValues[bar.Value]; special {
+			continue
+		}
+		if bar.Count > maxCount {
+			maxCount = bar.Count
+			modes = nil
+		}
+		if bar.Count == maxCount {
+			modes = append(modes, bar.Value)
+		}
+	}
+	return modes
+}
+
+// findNearestCard returns the value of the deck card nearest to the average, the higher card wins a tie
+// whatever the order of the deck is.
+func findNearestCard(deck Deck, average float64) string {
+	nearest := ""
+	nearestScore := 0.0
+	distance := math.Inf(1)
+	for _, card := range deck.Cards {
+		d := math.Abs(card.Score - average)
+		if d < distance || (d == distance && card.Score > nearestScore) {
+			nearest = card.Value
+			nearestScore = card.Score
+			distance = d
+		}
+	}
+	return nearest
+}
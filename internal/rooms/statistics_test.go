@@ -0,0 +1,259 @@
+package rooms
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func newTestCards(t *testing.T, deck Deck, values ...string) []Card {
+	t.Helper()
+	cards := make([]Card, 0, len(values))
+	for i, value := range values {
+		card, ok := FindCard(deck, value)
+		if !ok {
+			t.Fatalf("card %q is not in the deck", value)
+		}
+		player := Player{UserID: string(rune('a' + i))}
+		cards = append(cards, Card{Player: player, Value: card.Value, Score: card.Score, Special: card.Special})
+	}
+	return cards
+}
+
+func newTestDeck(t *testing.T, values ...string) Deck {
+	t.Helper()
+	deck, err := NewDeck(DeckCustom, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deck
+}
+
+func TestStatistics(t *testing.T) {
+	fibonacci := DefaultDeck()
+	tests := []struct {
+		name              string
+		deck              Deck
+		cards             []string
+		count             int
+		min               float64
+		max               float64
+		average           float64
+		median            float64
+		standardDeviation float64
+		modes             []string
+		consensus         bool
+		nearestCard       string
+	}{
+		{
+			name:  "no cards",
+			deck:  fibonacci,
+			count: 0,
+		},
+		{
+			name:  "only special cards",
+			deck:  fibonacci,
+			cards: []string{"?", "☕"},
+			count: 0,
+		},
+		{
+			name:        "single card",
+			deck:        fibonacci,
+			cards:       []string{"5"},
+			count:       1,
+			min:         5,
+			max:         5,
+			average:     5,
+			median:      5,
+			modes:       []string{"5"},
+			nearestCard: "5",
+		},
+		{
+			name:              "odd number of cards",
+			deck:              fibonacci,
+			cards:             []string{"8", "1", "3"},
+			count:             3,
+			min:               1,
+			max:               8,
+			average:           4,
+			median:            3,
+			standardDeviation: math.Sqrt(26.0 / 3),
+			modes:             []string{"1", "3", "8"},
+			nearestCard:       "5",
+		},
+		{
+			name:              "even number of cards",
+			deck:              fibonacci,
+			cards:             []string{"2", "8", "3", "3"},
+			count:             4,
+			min:               2,
+			max:               8,
+			average:           4,
+			median:            3,
+			standardDeviation: math.Sqrt(5.5),
+			modes:             []string{"3"},
+			nearestCard:       "5",
+		},
+		{
+			name:              "several modes",
+			deck:              fibonacci,
+			cards:             []string{"5", "3", "3", "5", "8"},
+			count:             5,
+			min:               3,
+			max:               8,
+			average:           4.8,
+			median:            5,
+			standardDeviation: math.Sqrt(3.36),
+			modes:             []string{"3", "5"},
+			nearestCard:       "5",
+		},
+		{
+			name:        "single card with special cards",
+			deck:        fibonacci,
+			cards:       []string{"5", "?", "☕"},
+			count:       1,
+			min:         5,
+			max:         5,
+			average:     5,
+			median:      5,
+			modes:       []string{"5"},
+			nearestCard: "5",
+		},
+		{
+			name:        "consensus with special cards",
+			deck:        fibonacci,
+			cards:       []string{"5", "?", "5", "∞"},
+			count:       2,
+			min:         5,
+			max:         5,
+			average:     5,
+			median:      5,
+			modes:       []string{"5"},
+			consensus:   true,
+			nearestCard: "5",
+		},
+		{
+			name:              "higher card wins a tie",
+			deck:              fibonacci,
+			cards:             []string{"2", "3"},
+			count:             2,
+			min:               2,
+			max:               3,
+			average:           2.5,
+			median:            2.5,
+			standardDeviation: 0.5,
+			modes:             []string{"2", "3"},
+			nearestCard:       "3",
+		},
+		{
+			name:              "higher card wins a tie in a descending deck",
+			deck:              newTestDeck(t, "8", "3", "2", "1"),
+			cards:             []string{"2", "3"},
+			count:             2,
+			min:               2,
+			max:               3,
+			average:           2.5,
+			median:            2.5,
+			standardDeviation: 0.5,
+			modes:             []string{"3", "2"},
+			nearestCard:       "3",
+		},
+		{
+			name:              "higher card wins a tie in an unordered deck",
+			deck:              newTestDeck(t, "8", "1", "3"),
+			cards:             []string{"1", "3"},
+			count:             2,
+			min:               1,
+			max:               3,
+			average:           2,
+			median:            2,
+			standardDeviation: 1,
+			modes:             []string{"1", "3"},
+			nearestCard:       "3",
+		},
+		{
+			name:              "cards scored by positions",
+			deck:              newTestDeck(t, "small", "medium", "large"),
+			cards:             []string{"small", "large"},
+			count:             2,
+			min:               1,
+			max:               3,
+			average:           2,
+			median:            2,
+			standardDeviation: 1,
+			modes:             []string{"small", "large"},
+			nearestCard:       "medium",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statistics := NewStatistics(newTestCards(t, test.deck, test.cards...), test.deck)
+			if statistics.Count != test.count {
+				t.Errorf("Count = %d, want %d", statistics.Count, test.count)
+			}
+			if statistics.Min != test.min || statistics.Max != test.max {
+				t.Errorf("Min, Max = %v, %v, want %v, %v", statistics.Min, statistics.Max, test.min, test.max)
+			}
+			if math.Abs(statistics.Average-test.average) > 1e-9 {
+				t.Errorf("Average = %v, want %v", statistics.Average, test.average)
+			}
+			if statistics.Median != test.median {
+				t.Errorf("Median = %v, want %v", statistics.Median, test.median)
+			}
+			if math.Abs(statistics.StandardDeviation-test.standardDeviation) > 1e-9 {
+				t.Errorf("StandardDeviation = %v, want %v", statistics.StandardDeviation, test.standardDeviation)
+			}
+			if !slices.Equal(statistics.Modes, test.modes) {
+				t.Errorf("Modes = %v, want %v", statistics.Modes, test.modes)
+			}
+			if statistics.Consensus != test.consensus {
+				t.Errorf("Consensus = %v, want %v", statistics.Consensus, test.consensus)
+			}
+			if statistics.NearestCard != test.nearestCard {
+				t.Errorf("NearestCard = %q, want %q", statistics.NearestCard, test.nearestCard)
+			}
+		})
+	}
+}
+
+func TestStatisticsHistogram(t *testing.T) {
+	tests := []struct {
+		name  string
+		deck  Deck
+		cards []string
+		want  []HistogramBar
+	}{
+		{
+			name:  "deck order",
+			deck:  DefaultDeck(),
+			cards: []string{"8", "1", "8", "3"},
+			want:  []HistogramBar{{Value: "1", Count: 1}, {Value: "3", Count: 1}, {Value: "8", Count: 2}},
+		},
+		{
+			name:  "special cards go last",
+			deck:  DefaultDeck(),
+			cards: []string{"?", "5", "☕", "2", "?"},
+			want:  []HistogramBar{{Value: "2", Count: 1}, {Value: "5", Count: 1}, {Value: "?", Count: 2}, {Value: "☕", Count: 1}},
+		},
+		{
+			name:  "order of a custom deck",
+			deck:  newTestDeck(t, "L", "M", "S"),
+			cards: []string{"S", "L", "M", "S"},
+			want:  []HistogramBar{{Value: "L", Count: 1}, {Value: "M", Count: 1}, {Value: "S", Count: 2}},
+		},
+		{
+			name:  "legacy game without a deck",
+			deck:  Deck{},
+			cards: []string{"13", "2", "5"},
+			want:  []HistogramBar{{Value: "2", Count: 1}, {Value: "5", Count: 1}, {Value: "13", Count: 1}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statistics := NewStatistics(newTestCards(t, test.deck, test.cards...), test.deck)
+			if !slices.Equal(statistics.Histogram, test.want) {
+				t.Errorf("Histogram = %v, want %v", statistics.Histogram, test.want)
+			}
+		})
+	}
+}
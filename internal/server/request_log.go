@@ -0,0 +1,35 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// formatRequestLog formats requests like the default gin logger, access tokens given in the query are redacted.
+func formatRequestLog(param gin.LogFormatterParams) string {
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		redactAccessToken(param.Path),
+		param.ErrorMessage,
+	)
+}
+
+func redactAccessToken(path string) string {
+	parsed, err := url.Parse(path)
+	if err != nil {
+		return path
+	}
+	query := parsed.Query()
+	if !query.Has("access_token") {
+		return path
+	}
+	query.Set("access_token", "REDACTED")
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
@@ -1,34 +1,137 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydata"
+	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydomain"
 	"aleksandersh.github.io/planning-poker-server/internal/controller"
-	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdata"
+	"aleksandersh.github.io/planning-poker-server/internal/oidc"
 	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdomain"
-	"aleksandersh.github.io/planning-poker-server/internal/users/usersdata"
 	"aleksandersh.github.io/planning-poker-server/internal/users/usersdomain"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/jwtutils"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/signutils"
 	"github.com/gin-gonic/gin"
 )
 
-func Start(address string) {
-	router := gin.Default()
+const (
+	shutdownTimeout = 5 * time.Second
+	// gamesSchedulerInterval is a precision of completing games on time.
+	gamesSchedulerInterval = time.Second
+
+	authModeSession = "session"
+	authModeJWT     = "jwt"
+
+	// Purposes of payloads signed by the link signing key, each purpose signs with its own derived key.
+	signPurposeInviteLink = "invite_link"
+	signPurposeLoginState = "login_state"
+)
+
+type Config struct {
+	Address string
+	// Storage is an empty string for in-memory storage or "sqlite://<path>" for a SQLite database.
+	Storage string
+	// SnapshotPath is a file the in-memory state is restored from and saved to, snapshots are disabled if it is empty.
+	SnapshotPath     string
+	SnapshotInterval time.Duration
+	// LinkSigningKey signs invite links and states of logins, a random key is used if it is empty.
+	LinkSigningKey []byte
+	// TokenTTL is a lifetime of access tokens, clients refresh tokens before they expire.
+	TokenTTL time.Duration
+	// AuthMode is "session" (default) for opaque access tokens of stored sessions
+	// or "jwt" for stateless signed tokens verified by the JWT keys.
+	AuthMode string
+	// JWTKeys are comma separated "<kid>:<algorithm>:<base64 key>" keys, the first one signs new tokens.
+	JWTKeys string
+	// OIDC enables login by an OpenID Connect provider if the issuer is set.
+	OIDC oidc.Config
+	// OIDCClientURL is a page of the client which the login callback redirects to with the access token.
+	OIDCClientURL string
+	Activity      activitydomain.Config
+}
+
+// Start serves the API until the process receives an interrupt or termination signal.
+func Start(config Config) error {
+	ur, rr, err := newRepositories(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	router := gin.New()
+	router.Use(gin.LoggerWithFormatter(formatRequestLog), gin.Recovery())
+
+	linkSigningKey := config.LinkSigningKey
+	if len(linkSigningKey) == 0 {
+		log.Println("invite link signing key is not set, links and logins in progress will be invalidated on restart")
+		linkSigningKey = signutils.GenerateKey()
+	}
+	signer := signutils.NewSigner(linkSigningKey)
 
 	ar := activitydata.NewRepository()
-	us := usersdomain.NewService(usersdata.NewRepo(), ar)
-	uc := controller.NewUsersController(us)
+	tokenCodec, err := newTokenCodec(config)
+	if err != nil {
+		return err
+	}
+	us := usersdomain.NewService(ur, ar, rr, config.TokenTTL, tokenCodec)
+	ah := controller.NewAuthHelper(us)
+	uc := controller.NewUsersController(ah, us)
 
 	router.POST("/v1/users/register", uc.Register)
+	router.POST("/v1/users/token/refresh", uc.RefreshToken)
+	router.POST("/v1/users/logout", uc.Logout)
+	router.GET("/v1/users/sessions", uc.GetSessions)
+	router.DELETE("/v1/users/sessions", uc.DeleteSessions)
+	router.DELETE("/v1/users/sessions/:session_id", uc.DeleteSession)
+	router.GET("/v1/users/me", uc.GetMe)
+	router.PATCH("/v1/users/me", uc.PatchMe)
+	router.DELETE("/v1/users/me", uc.DeleteMe)
 
-	ah := controller.NewAuthHelper(us)
-	rr := roomsdata.NewRepo()
-	rs := roomsdomain.NewRoomsService(rr, ar)
+	if config.OIDC.Issuer != "" {
+		if clientURL, err := url.Parse(config.OIDCClientURL); err != nil || !clientURL.IsAbs() || clientURL.Fragment != "" {
+			return errors.New("client URL of the OpenID Connect login must be an absolute URL without a fragment")
+		}
+		ls := usersdomain.NewLoginService(us, oidc.NewProvider(config.OIDC), signer.ForPurpose(signPurposeLoginState))
+		lc := controller.NewLoginController(ls, config.OIDCClientURL)
+
+		router.GET("/v1/auth/oidc/login", lc.Start)
+		router.GET("/v1/auth/oidc/callback", lc.Callback)
+	}
+
+	rs := roomsdomain.NewRoomsService(rr, ar, signer.ForPurpose(signPurposeInviteLink))
 	rc := controller.NewRoomsController(ah, rs)
 
 	router.POST("/v1/rooms", rc.Post)
 	router.GET("/v1/rooms/:room_id", rc.Get)
 	router.DELETE("/v1/rooms/:room_id", rc.Delete)
 	router.POST("/v1/rooms/:room_id/join", rc.Join)
+	router.POST("/v1/rooms/:room_id/leave", rc.Leave)
+	router.DELETE("/v1/rooms/:room_id/players/:user_id", rc.KickPlayer)
+	router.PUT("/v1/rooms/:room_id/players/:user_id/role", rc.PutPlayerRole)
+	router.PUT("/v1/rooms/:room_id/owner", rc.PutOwner)
 	router.GET("/v1/rooms/:room_id/state", rc.GetState)
+	router.GET("/v1/rooms/:room_id/ws", rc.Subscribe)
+	router.GET("/v1/rooms/:room_id/events", rc.Events)
+	router.GET("/v1/rooms/:room_id/log", rc.GetLog)
+
+	ic := controller.NewInviteCodesController(ah, rs)
+
+	router.POST("/v1/rooms/:room_id/invite-codes", ic.Post)
+	router.GET("/v1/rooms/:room_id/invite-codes", ic.GetAll)
+	router.DELETE("/v1/rooms/:room_id/invite-codes/:code", ic.Delete)
+	router.POST("/v1/rooms/:room_id/invite-links", ic.PostLink)
 
 	gs := roomsdomain.NewGamesService(rr, ar)
 	gc := controller.NewGamesController(ah, gs)
@@ -38,6 +141,58 @@ func Start(address string) {
 	router.POST("/v1/games/:game_id/reset", gc.Reset)
 	router.POST("/v1/games/:game_id/send-card", gc.SendCard)
 	router.POST("/v1/games/:game_id/drop-card", gc.DropCard)
+	router.POST("/v1/games/:game_id/rounds", gc.StartRound)
+	router.PUT("/v1/games/:game_id/final-estimate", gc.PutFinalEstimate)
+	router.POST("/v1/games/:game_id/timer", gc.StartTimer)
+	router.POST("/v1/games/:game_id/timer/extend", gc.ExtendTimer)
+	router.DELETE("/v1/games/:game_id/timer", gc.CancelTimer)
+
+	go activitydomain.NewWatcher(config.Activity, ar, rr, ur).Run(ctx)
+	go roomsdomain.NewGamesScheduler(rr, gamesSchedulerInterval).Run(ctx)
+
+	var snapshots *snapshotter
+	if config.SnapshotPath != "" {
+		snapshots = &snapshotter{path: config.SnapshotPath, usersRepository: ur, roomsRepository: rr}
+		go snapshots.run(ctx, config.SnapshotInterval)
+	}
+
+	httpServer := &http.Server{
+		Addr:        config.Address,
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	err = httpServer.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	if snapshots != nil {
+		if err := snapshots.save(); err != nil {
+			return fmt.Errorf("failed to save snapshot: %w", err)
+		}
+	}
+	return nil
+}
 
-	router.Run(address)
+// newTokenCodec creates a codec of stateless access tokens for the JWT auth mode, it is nil for the session mode.
+func newTokenCodec(config Config) (*jwtutils.Codec, error) {
+	switch config.AuthMode {
+	case "", authModeSession:
+		return nil, nil
+	case authModeJWT:
+		keys, err := jwtutils.ParseKeys(config.JWTKeys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JWT keys: %w", err)
+		}
+		return jwtutils.NewCodec(keys)
+	default:
+		return nil, fmt.Errorf("unsupported auth mode %q", config.AuthMode)
+	}
 }
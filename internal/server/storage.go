@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdata"
+	"aleksandersh.github.io/planning-poker-server/internal/storage/snapshot"
+	"aleksandersh.github.io/planning-poker-server/internal/storage/sqlitestorage"
+	"aleksandersh.github.io/planning-poker-server/internal/users/usersdata"
+)
+
+const (
+	storageMemory = "memory"
+	storageSQLite = "sqlite://"
+)
+
+// newRepositories creates repositories backed by the configured storage,
+// in-memory repositories are restored from the snapshot file if it is set.
+func newRepositories(config Config) (*usersdata.Repository, *roomsdata.Repository, error) {
+	if config.Storage == "" || config.Storage == storageMemory {
+		if config.SnapshotPath == "" {
+			return usersdata.NewRepo(), roomsdata.NewRepo(), nil
+		}
+		s, err := snapshot.Read(config.SnapshotPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		return newPersistentRepositories(snapshot.NewStorage(s), snapshot.NewStorage(s))
+	}
+
+	if config.SnapshotPath != "" {
+		return nil, nil, errors.New("snapshots are supported only for the memory storage")
+	}
+
+	path, found := strings.CutPrefix(config.Storage, storageSQLite)
+	if !found || path == "" {
+		return nil, nil, fmt.Errorf("unsupported storage %q", config.Storage)
+	}
+
+	db, err := sqlitestorage.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return newPersistentRepositories(sqlitestorage.NewUsersStorage(db), sqlitestorage.NewRoomsStorage(db))
+}
+
+func newPersistentRepositories(usersStorage usersdata.Storage, roomsStorage roomsdata.Storage) (*usersdata.Repository, *roomsdata.Repository, error) {
+	ur, err := usersdata.NewPersistentRepo(usersStorage)
+	if err != nil {
+		return nil, nil, err
+	}
+	rr, err := roomsdata.NewPersistentRepo(roomsStorage)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ur, rr, nil
+}
+
+type snapshotter struct {
+	path            string
+	usersRepository *usersdata.Repository
+	roomsRepository *roomsdata.Repository
+}
+
+func (s *snapshotter) save() error {
+	usersList, sessions, identities := s.usersRepository.Export()
+	return snapshot.Write(s.path, snapshot.Snapshot{
+		CreatedAt:   time.Now(),
+		Users:       usersList,
+		Sessions:    sessions,
+		Identities:  identities,
+		Revocations: s.usersRepository.ExportRevocations(),
+		Events:      s.roomsRepository.Export(),
+	})
+}
+
+// run saves snapshots on the interval until the context is done.
+func (s *snapshotter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.save(); err != nil {
+				log.Println(fmt.Errorf("failed to save snapshot: %w", err))
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/idutils"
+)
+
+// FormatVersion is increased on incompatible changes of the snapshot format,
+// new fields are added without changing the version since unknown fields are ignored on read.
+const FormatVersion = 4
+
+// legacySessionTTL is a lifetime given to access tokens of old snapshots, which never expired.
+const legacySessionTTL = 7 * 24 * time.Hour
+
+type Snapshot struct {
+	Version    int              `json:"version"`
+	CreatedAt  time.Time        `json:"created_at"`
+	Users      []users.User     `json:"users"`
+	Sessions   []users.Session  `json:"sessions"`
+	Identities []users.Identity `json:"identities"`
+	// Revocations reject stateless access tokens of deleted users after a restart.
+	Revocations []users.Revocation `json:"revocations"`
+	Events      []rooms.Event      `json:"events"`
+}
+
+// snapshotV1 contains the fields of the first version, which kept rooms and games instead of events.
+type snapshotV1 struct {
+	Rooms []rooms.Room `json:"rooms"`
+	Games []rooms.Game `json:"games"`
+}
+
+// snapshotV2 contains the fields of the versions before sessions, which kept user IDs by access tokens.
+type snapshotV2 struct {
+	AccessTokens map[string]string `json:"access_tokens"`
+}
+
+// Read reads the snapshot file, a missing file gives an empty snapshot.
+func Read(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Snapshot{Version: FormatVersion}, nil
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if snapshot.Version < 1 || snapshot.Version > FormatVersion {
+		return Snapshot{}, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+	if snapshot.Version == 1 {
+		var v1 snapshotV1
+		if err := json.Unmarshal(data, &v1); err != nil {
+			return Snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+		snapshot.Events = restoreRoomEvents(v1.Rooms, v1.Games, snapshot.CreatedAt)
+	}
+	if snapshot.Version <= 2 {
+		var v2 snapshotV2
+		if err := json.Unmarshal(data, &v2); err != nil {
+			return Snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+		snapshot.Sessions = restoreSessions(v2.AccessTokens, snapshot.CreatedAt)
+	}
+	return snapshot, nil
+}
+
+// restoreRoomEvents creates an event restoring every room together with its games.
+func restoreRoomEvents(roomsList []rooms.Room, games []rooms.Game, createdAt time.Time) []rooms.Event {
+	roomGames := make(map[string][]rooms.Game)
+	for _, game := range games {
+		roomGames[game.RoomID] = append(roomGames[game.RoomID], game)
+	}
+
+	events := make([]rooms.Event, 0, len(roomsList))
+	for _, room := range roomsList {
+		events = append(events, rooms.Event{
+			RoomID:    room.ID,
+			Sequence:  1,
+			Type:      rooms.EventRoomRestored,
+			CreatedAt: createdAt,
+			Commit:    room.Commit,
+			Room:      &room,
+			Games:     roomGames[room.ID],
+		})
+	}
+	return events
+}
+
+// restoreSessions creates a session for every access token, the tokens expire after the legacy lifetime.
+func restoreSessions(accessTokens map[string]string, createdAt time.Time) []users.Session {
+	expiresAt := time.Now().Add(legacySessionTTL)
+	sessions := make([]users.Session, 0, len(accessTokens))
+	for accessToken, userID := range accessTokens {
+		sessions = append(sessions, users.Session{
+			ID:          idutils.GenerateID(),
+			UserID:      userID,
+			AccessToken: accessToken,
+			CreatedAt:   createdAt,
+			ExpiresAt:   expiresAt,
+		})
+	}
+	return sessions
+}
+
+// Write replaces the snapshot file atomically, the snapshot is written to a temporary file
+// in the same directory which is renamed afterwards.
+func Write(path string, snapshot Snapshot) error {
+	snapshot.Version = FormatVersion
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
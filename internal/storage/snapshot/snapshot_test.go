@@ -0,0 +1,142 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+)
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	room := rooms.Room{ID: "room", Commit: "1", Sequence: 1, Name: "Room", Owner: "user", Players: []rooms.Player{{UserID: "user", Name: "Alice"}}}
+	snapshot := Snapshot{
+		CreatedAt:  createdAt,
+		Users:      []users.User{{ID: "user", Name: "Alice", Color: "FF0000"}},
+		Sessions:   []users.Session{{ID: "session", UserID: "user", AccessToken: "token", CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)}},
+		Identities: []users.Identity{{Issuer: "https://issuer.example", Subject: "subject", UserID: "user"}},
+		Events: []rooms.Event{
+			{RoomID: "room", Sequence: 1, Type: rooms.EventRoomCreated, CreatedAt: createdAt, Commit: "1", UserID: "user", Room: &room},
+		},
+	}
+
+	if err := Write(path, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot.Version = FormatVersion
+	if !reflect.DeepEqual(read, snapshot) {
+		t.Errorf("Read() = %v, want %v", read, snapshot)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("files after Write() = %v, want only the snapshot", entries)
+	}
+}
+
+func TestReadMissingFile(t *testing.T) {
+	snapshot, err := Read(filepath.Join(t.TempDir(), "snapshot.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(snapshot, Snapshot{Version: FormatVersion}) {
+		t.Errorf("Read() = %v, want an empty snapshot", snapshot)
+	}
+}
+
+func TestReadOlderVersions(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "rooms and games of version 1",
+			data: `{
+				"version": 1,
+				"created_at": "2024-01-01T12:00:00Z",
+				"users": [{"ID": "user", "Name": "Alice"}],
+				"rooms": [{"ID": "room", "Commit": "commit", "Name": "Room", "Owner": "user", "Games": ["game"]}],
+				"games": [{"ID": "game", "RoomID": "room", "Name": "Game"}],
+				"access_tokens": {"token": "user"}
+			}`,
+		},
+		{
+			name: "access tokens of version 2",
+			data: `{
+				"version": 2,
+				"created_at": "2024-01-01T12:00:00Z",
+				"users": [{"ID": "user", "Name": "Alice"}],
+				"events": [{
+					"RoomID": "room", "Sequence": 1, "Type": "room_restored", "CreatedAt": "2024-01-01T12:00:00Z", "Commit": "commit",
+					"Room": {"ID": "room", "Commit": "commit", "Name": "Room", "Owner": "user", "Games": ["game"]},
+					"Games": [{"ID": "game", "RoomID": "room", "Name": "Game"}]
+				}],
+				"access_tokens": {"token": "user"}
+			}`,
+		},
+	}
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.json")
+			if err := os.WriteFile(path, []byte(test.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			snapshot, err := Read(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(snapshot.Users) != 1 || snapshot.Users[0].ID != "user" {
+				t.Errorf("Users = %v, want the user", snapshot.Users)
+			}
+			if len(snapshot.Sessions) != 1 {
+				t.Fatalf("Sessions = %v, want a session of the access token", snapshot.Sessions)
+			}
+			session := snapshot.Sessions[0]
+			if session.UserID != "user" || session.AccessToken != "token" || session.ID == "" || !session.CreatedAt.Equal(createdAt) {
+				t.Errorf("session = %v, want a session of the access token created with the snapshot", session)
+			}
+			if !session.ExpiresAt.After(time.Now()) {
+				t.Errorf("session expires at %v, want it in the future", session.ExpiresAt)
+			}
+
+			if len(snapshot.Events) != 1 {
+				t.Fatalf("Events = %v, want a restoring event", snapshot.Events)
+			}
+			event := snapshot.Events[0]
+			if event.Type != rooms.EventRoomRestored || event.RoomID != "room" || event.Sequence != 1 || event.Commit != "commit" {
+				t.Errorf("event = %s of %s with sequence %d and commit %q, want %s of room with sequence 1 and commit %q",
+					event.Type, event.RoomID, event.Sequence, event.Commit, rooms.EventRoomRestored, "commit")
+			}
+			if event.Room == nil || event.Room.Name != "Room" {
+				t.Errorf("event room = %v, want the restored room", event.Room)
+			}
+			if len(event.Games) != 1 || event.Games[0].ID != "game" {
+				t.Errorf("event games = %v, want the restored game", event.Games)
+			}
+		})
+	}
+}
+
+func TestReadUnsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(path, []byte(`{"version": 100}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Errorf("Read() of an unsupported version succeeded, want an error")
+	}
+}
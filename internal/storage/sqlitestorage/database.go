@@ -0,0 +1,128 @@
+package sqlitestorage
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// migrations are applied in order, the index of the last applied one is kept in the user_version pragma.
+var migrations = []string{
+	`CREATE TABLE users (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL
+	);
+	CREATE TABLE access_tokens (
+		token TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL
+	);
+	CREATE TABLE rooms (
+		id TEXT PRIMARY KEY,
+		data TEXT NOT NULL
+	);
+	CREATE TABLE games (
+		id TEXT PRIMARY KEY,
+		room_id TEXT NOT NULL,
+		data TEXT NOT NULL
+	);
+	CREATE INDEX games_room_id ON games (room_id);`,
+	// rooms and games are replaced by the event log, every existing room gets a restoring event with its games
+	`CREATE TABLE room_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		room_id TEXT NOT NULL,
+		sequence INTEGER NOT NULL,
+		data TEXT NOT NULL
+	);
+	CREATE INDEX room_events_room_id ON room_events (room_id, sequence);
+	INSERT INTO room_events (room_id, sequence, data)
+	SELECT r.id, 1, json_object(
+		'RoomID', r.id,
+		'Sequence', 1,
+		'Type', 'room_restored',
+		'CreatedAt', strftime('%Y-%m-%dT%H:%M:%SZ', 'now'),
+		'Commit', json_extract(r.data, '$.Commit'),
+		'Room', json(r.data),
+		'Games', (SELECT json_group_array(json(g.data)) FROM games g WHERE g.room_id = r.id)
+	)
+	FROM rooms r;
+	DROP TABLE games;
+	DROP TABLE rooms;`,
+	// access tokens become sessions with an expiration time, existing tokens are given a week to be refreshed
+	`CREATE TABLE sessions (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		token TEXT NOT NULL UNIQUE,
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL
+	);
+	CREATE INDEX sessions_user_id ON sessions (user_id);
+	INSERT INTO sessions (id, user_id, token, created_at, expires_at)
+	SELECT lower(hex(randomblob(16))), user_id, token, strftime('%s', 'now'), strftime('%s', 'now', '+7 days')
+	FROM access_tokens;
+	DROP TABLE access_tokens;`,
+	`CREATE TABLE identities (
+		issuer TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		PRIMARY KEY (issuer, subject)
+	);
+	CREATE INDEX identities_user_id ON identities (user_id);`,
+	`ALTER TABLE users ADD COLUMN color TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN avatar TEXT NOT NULL DEFAULT '';`,
+	`CREATE TABLE revocations (
+		user_id TEXT PRIMARY KEY,
+		expires_at INTEGER NOT NULL
+	);`,
+}
+
+// Open opens the database file, creating it if needed, and migrates its schema to the latest version.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("unsupported schema version %d", version)
+	}
+
+	for ; version < len(migrations); version++ {
+		err := inTransaction(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migrations[version]); err != nil {
+				return err
+			}
+			_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to migrate schema to version %d: %w", version+1, err)
+		}
+	}
+	return nil
+}
+
+func inTransaction(db *sql.DB, block func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := block(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,117 @@
+package sqlitestorage
+
+import (
+	"database/sql"
+	"testing"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func readSchemaVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func TestMigrateEmptyDatabase(t *testing.T) {
+	db := openTestDB(t)
+	if version := readSchemaVersion(t, db); version != len(migrations) {
+		t.Fatalf("schema version = %d, want %d", version, len(migrations))
+	}
+	for _, table := range []string{"users", "sessions", "identities", "room_events"} {
+		if _, err := db.Exec("SELECT * FROM " + table); err != nil {
+			t.Errorf("table %s: %v", table, err)
+		}
+	}
+
+	if err := migrate(db); err != nil {
+		t.Fatalf("migrate() of the latest schema error = %v", err)
+	}
+	if _, err := db.Exec("PRAGMA user_version = 100"); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(db); err == nil {
+		t.Errorf("migrate() of an unsupported schema succeeded, want an error")
+	}
+}
+
+func TestMigrateFromFirstVersion(t *testing.T) {
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	statements := []string{
+		migrations[0],
+		"PRAGMA user_version = 1",
+		`INSERT INTO users (id, name) VALUES ('user', 'Alice')`,
+		`INSERT INTO access_tokens (token, user_id) VALUES ('token', 'user')`,
+		`INSERT INTO rooms (id, data) VALUES ('room', '{"ID":"room","Commit":"commit","Name":"Room","Owner":"user","Games":["game"]}')`,
+		`INSERT INTO games (id, room_id, data) VALUES ('game', 'room', '{"ID":"game","RoomID":"room","Name":"Game"}')`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if version := readSchemaVersion(t, db); version != len(migrations) {
+		t.Fatalf("schema version = %d, want %d", version, len(migrations))
+	}
+
+	usersStorage := NewUsersStorage(db)
+	usersList, err := usersStorage.LoadUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usersList) != 1 || usersList[0].ID != "user" || usersList[0].Name != "Alice" || usersList[0].Color != "" {
+		t.Errorf("LoadUsers() = %v, want the user without a color", usersList)
+	}
+	sessions, err := usersStorage.LoadSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].AccessToken != "token" || sessions[0].UserID != "user" || sessions[0].ID == "" {
+		t.Errorf("LoadSessions() = %v, want a session of the access token", sessions)
+	}
+	if !sessions[0].ExpiresAt.After(sessions[0].CreatedAt) {
+		t.Errorf("session expires at %v, want it after the creation at %v", sessions[0].ExpiresAt, sessions[0].CreatedAt)
+	}
+
+	events, err := NewRoomsStorage(db).LoadEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("LoadEvents() = %v, want a restoring event", events)
+	}
+	event := events[0]
+	if event.Type != rooms.EventRoomRestored || event.RoomID != "room" || event.Sequence != 1 || event.Commit != "commit" {
+		t.Errorf("event = %s of %s with sequence %d and commit %q, want %s of room with sequence 1 and commit %q",
+			event.Type, event.RoomID, event.Sequence, event.Commit, rooms.EventRoomRestored, "commit")
+	}
+	if event.Room == nil || event.Room.Name != "Room" {
+		t.Errorf("event room = %v, want the restored room", event.Room)
+	}
+	if len(event.Games) != 1 || event.Games[0].ID != "game" || event.Games[0].Name != "Game" {
+		t.Errorf("event games = %v, want the restored game", event.Games)
+	}
+}
@@ -0,0 +1,67 @@
+package sqlitestorage
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+)
+
+// RoomsStorage keeps the room events as JSON documents.
+type RoomsStorage struct {
+	db *sql.DB
+}
+
+func NewRoomsStorage(db *sql.DB) *RoomsStorage {
+	return &RoomsStorage{db: db}
+}
+
+func (s *RoomsStorage) LoadEvents() ([]rooms.Event, error) {
+	rows, err := s.db.Query("SELECT data FROM room_events ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []rooms.Event
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var event rooms.Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (s *RoomsStorage) AppendEvent(event rooms.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT INTO room_events (room_id, sequence, data) VALUES (?, ?, ?)", event.RoomID, event.Sequence, data)
+	return err
+}
+
+func (s *RoomsStorage) ReplaceEvents(roomID string, events []rooms.Event) error {
+	return inTransaction(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM room_events WHERE room_id = ?", roomID); err != nil {
+			return err
+		}
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec("INSERT INTO room_events (room_id, sequence, data) VALUES (?, ?, ?)", event.RoomID, event.Sequence, data)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,49 @@
+package sqlitestorage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+)
+
+func TestRoomsStorage(t *testing.T) {
+	s := NewRoomsStorage(openTestDB(t))
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	room := rooms.Room{ID: "room", Commit: "1", Sequence: 1, Name: "Room", Owner: "user", Players: []rooms.Player{{UserID: "user", Name: "Alice"}}}
+	game := rooms.Game{ID: "game", RoomID: "room", Name: "Game", Status: rooms.GameStatusActive, Cards: []rooms.Card{{Player: room.Players[0], Value: "5", Score: 5}}}
+	events := []rooms.Event{
+		{RoomID: "room", Sequence: 1, Type: rooms.EventRoomCreated, CreatedAt: createdAt, Commit: "1", UserID: "user", Room: &room},
+		{RoomID: "other", Sequence: 1, Type: rooms.EventRoomCreated, CreatedAt: createdAt, Commit: "2", UserID: "user", Room: &rooms.Room{ID: "other"}},
+		{RoomID: "room", Sequence: 2, Type: rooms.EventCardSent, CreatedAt: createdAt, Commit: "3", UserID: "user", Games: []rooms.Game{game}},
+	}
+	for _, event := range events {
+		if err := s.AppendEvent(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded, err := s.LoadEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, events) {
+		t.Errorf("LoadEvents() = %v, want %v", loaded, events)
+	}
+
+	replacement := []rooms.Event{
+		{RoomID: "room", Sequence: 2, Type: rooms.EventRoomCompacted, CreatedAt: createdAt, Commit: "3", Room: &room, Games: []rooms.Game{game}},
+	}
+	if err := s.ReplaceEvents("room", replacement); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = s.LoadEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rooms.Event{events[1], replacement[0]}
+	if !reflect.DeepEqual(loaded, want) {
+		t.Errorf("LoadEvents() after ReplaceEvents() = %v, want %v", loaded, want)
+	}
+}
@@ -0,0 +1,163 @@
+package sqlitestorage
+
+import (
+	"database/sql"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+)
+
+type UsersStorage struct {
+	db *sql.DB
+}
+
+func NewUsersStorage(db *sql.DB) *UsersStorage {
+	return &UsersStorage{db: db}
+}
+
+func (s *UsersStorage) LoadUsers() ([]users.User, error) {
+	rows, err := s.db.Query("SELECT id, name, color, avatar FROM users")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []users.User
+	for rows.Next() {
+		var user users.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Color, &user.Avatar); err != nil {
+			return nil, err
+		}
+		result = append(result, user)
+	}
+	return result, rows.Err()
+}
+
+func (s *UsersStorage) LoadSessions() ([]users.Session, error) {
+	rows, err := s.db.Query("SELECT id, user_id, token, created_at, expires_at FROM sessions")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []users.Session
+	for rows.Next() {
+		var session users.Session
+		var createdAt, expiresAt int64
+		if err := rows.Scan(&session.ID, &session.UserID, &session.AccessToken, &createdAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		session.CreatedAt = time.Unix(createdAt, 0)
+		session.ExpiresAt = time.Unix(expiresAt, 0)
+		result = append(result, session)
+	}
+	return result, rows.Err()
+}
+
+func (s *UsersStorage) LoadIdentities() ([]users.Identity, error) {
+	rows, err := s.db.Query("SELECT issuer, subject, user_id FROM identities")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []users.Identity
+	for rows.Next() {
+		var identity users.Identity
+		if err := rows.Scan(&identity.Issuer, &identity.Subject, &identity.UserID); err != nil {
+			return nil, err
+		}
+		result = append(result, identity)
+	}
+	return result, rows.Err()
+}
+
+func (s *UsersStorage) SaveUser(user users.User) error {
+	_, err := s.db.Exec(
+		"INSERT OR REPLACE INTO users (id, name, color, avatar) VALUES (?, ?, ?, ?)",
+		user.ID, user.Name, user.Color, user.Avatar,
+	)
+	return err
+}
+
+func (s *UsersStorage) SaveSession(session users.Session) error {
+	_, err := s.db.Exec(
+		"INSERT OR REPLACE INTO sessions (id, user_id, token, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+		session.ID, session.UserID, session.AccessToken, session.CreatedAt.Unix(), session.ExpiresAt.Unix(),
+	)
+	return err
+}
+
+func (s *UsersStorage) DeleteSessions(sessionIDs []string) error {
+	return inTransaction(s.db, func(tx *sql.Tx) error {
+		for _, sessionID := range sessionIDs {
+			if _, err := tx.Exec("DELETE FROM sessions WHERE id = ?", sessionID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *UsersStorage) SaveIdentity(identity users.Identity) error {
+	_, err := s.db.Exec(
+		"INSERT INTO identities (issuer, subject, user_id) VALUES (?, ?, ?)",
+		identity.Issuer, identity.Subject, identity.UserID,
+	)
+	return err
+}
+
+func (s *UsersStorage) DeleteUser(userID string) error {
+	return inTransaction(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM identities WHERE user_id = ?", userID); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM users WHERE id = ?", userID)
+		return err
+	})
+}
+
+func (s *UsersStorage) LoadRevocations() ([]users.Revocation, error) {
+	rows, err := s.db.Query("SELECT user_id, expires_at FROM revocations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []users.Revocation
+	for rows.Next() {
+		var revocation users.Revocation
+		var expiresAt int64
+		if err := rows.Scan(&revocation.UserID, &expiresAt); err != nil {
+			return nil, err
+		}
+		revocation.ExpiresAt = time.Unix(expiresAt, 0)
+		result = append(result, revocation)
+	}
+	return result, rows.Err()
+}
+
+func (s *UsersStorage) SaveRevocation(revocation users.Revocation) error {
+	return inTransaction(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM revocations WHERE expires_at <= ?", time.Now().Unix()); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			"INSERT OR REPLACE INTO revocations (user_id, expires_at) VALUES (?, ?)",
+			revocation.UserID, revocation.ExpiresAt.Unix(),
+		)
+		return err
+	})
+}
+
+func (s *UsersStorage) IsRevoked(userID string, now time.Time) (bool, error) {
+	var revoked bool
+	err := s.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM revocations WHERE user_id = ? AND expires_at > ?)",
+		userID, now.Unix(),
+	).Scan(&revoked)
+	return revoked, err
+}
@@ -0,0 +1,91 @@
+package sqlitestorage
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+)
+
+func TestUsersStorage(t *testing.T) {
+	s := NewUsersStorage(openTestDB(t))
+	createdAt := time.Unix(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).Unix(), 0)
+	alice := users.User{ID: "alice", Name: "Alice", Color: "FF0000", Avatar: "🦊"}
+	bob := users.User{ID: "bob", Name: "Bob"}
+	sessions := []users.Session{
+		{ID: "s1", UserID: alice.ID, AccessToken: "t1", CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)},
+		{ID: "s2", UserID: alice.ID, AccessToken: "t2", CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)},
+		{ID: "s3", UserID: bob.ID, AccessToken: "t3", CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)},
+	}
+	identity := users.Identity{Issuer: "https://issuer.example", Subject: "subject", UserID: alice.ID}
+
+	for _, user := range []users.User{alice, bob} {
+		if err := s.SaveUser(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, session := range sessions {
+		if err := s.SaveSession(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SaveIdentity(identity); err != nil {
+		t.Fatal(err)
+	}
+	alice.Name = "Renamed"
+	if err := s.SaveUser(alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteSessions([]string{"s2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertUsers(t, s, []users.User{alice, bob})
+	assertSessions(t, s, []users.Session{sessions[0], sessions[2]})
+	assertIdentities(t, s, []users.Identity{identity})
+
+	if err := s.DeleteUser(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertUsers(t, s, []users.User{bob})
+	assertSessions(t, s, []users.Session{sessions[2]})
+	assertIdentities(t, s, nil)
+}
+
+func assertUsers(t *testing.T, s *UsersStorage, want []users.User) {
+	t.Helper()
+	got, err := s.LoadUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(got, func(a, b users.User) int { return strings.Compare(a.ID, b.ID) })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadUsers() = %v, want %v", got, want)
+	}
+}
+
+func assertSessions(t *testing.T, s *UsersStorage, want []users.Session) {
+	t.Helper()
+	got, err := s.LoadSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(got, func(a, b users.Session) int { return strings.Compare(a.ID, b.ID) })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadSessions() = %v, want %v", got, want)
+	}
+}
+
+func assertIdentities(t *testing.T, s *UsersStorage, want []users.Identity) {
+	t.Helper()
+	got, err := s.LoadIdentities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadIdentities() = %v, want %v", got, want)
+	}
+}
@@ -1,6 +1,49 @@
 package users
 
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrAccessTokenNotFound  = errors.New("access token not found")
+	ErrAccessTokenExpired   = errors.New("access token expired")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrSessionsNotSupported = errors.New("sessions are not supported by stateless access tokens")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrInvalidLoginState    = errors.New("invalid login state")
+	ErrLoginFailed          = errors.New("login failed")
+	ErrInvalidProfile       = errors.New("invalid profile")
+)
+
 type User struct {
 	ID   string
 	Name string
+	// Color is a preferred color of the user in rooms as "RRGGBB", a color of the room palette is used if it is empty.
+	Color string
+	// Avatar is a URL of an image or an emoji.
+	Avatar string
+}
+
+// Session is an access token issued to the user. The token is known only to the client,
+// the session is referred to by its ID everywhere else.
+type Session struct {
+	ID          string
+	UserID      string
+	AccessToken string
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Revocation rejects stateless access tokens of the deleted user, it is kept until all of them expire.
+type Revocation struct {
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// Identity links the user to an account of an external identity provider, so the user is the same on every login.
+type Identity struct {
+	Issuer  string
+	Subject string
+	UserID  string
 }
@@ -1,31 +1,85 @@
 package usersdata
 
 import (
-	"errors"
+	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	"aleksandersh.github.io/planning-poker-server/internal/users"
 	"aleksandersh.github.io/planning-poker-server/internal/utils/idutils"
 )
 
-var (
-	ErrAccessTokenNotFound = errors.New("access token not found")
-)
-
 type Repository struct {
-	mutex        sync.RWMutex
-	users        map[string]users.User
+	mutex    sync.RWMutex
+	storage  Storage
+	users    map[string]users.User
+	sessions map[string]users.Session
+	// accessTokens contains session IDs by access tokens.
 	accessTokens map[string]string
+	identities   map[identityKey]users.Identity
+	// revocations contain expiration times of revocations by user IDs.
+	revocations map[string]time.Time
+}
+
+type identityKey struct {
+	issuer  string
+	subject string
 }
 
 func NewRepo() *Repository {
 	return &Repository{
+		storage:      memoryStorage{},
 		users:        make(map[string]users.User),
+		sessions:     make(map[string]users.Session),
 		accessTokens: make(map[string]string),
+		identities:   make(map[identityKey]users.Identity),
+		revocations:  make(map[string]time.Time),
+	}
+}
+
+// NewPersistentRepo creates a repository which loads its state from the storage
+// and writes every change through it.
+func NewPersistentRepo(storage Storage) (*Repository, error) {
+	r := NewRepo()
+	r.storage = storage
+
+	loadedUsers, err := storage.LoadUsers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+	for _, user := range loadedUsers {
+		r.users[user.ID] = user
+	}
+
+	loadedSessions, err := storage.LoadSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
 	}
+	for _, session := range loadedSessions {
+		r.putSession(session)
+	}
+
+	loadedIdentities, err := storage.LoadIdentities()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identities: %w", err)
+	}
+	for _, identity := range loadedIdentities {
+		r.identities[identityKey{issuer: identity.Issuer, subject: identity.Subject}] = identity
+	}
+
+	loadedRevocations, err := storage.LoadRevocations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load revocations: %w", err)
+	}
+	for _, revocation := range loadedRevocations {
+		r.revocations[revocation.UserID] = revocation.ExpiresAt
+	}
+
+	return r, nil
 }
 
-func (r *Repository) CreateUser(user users.User) users.User {
+func (r *Repository) CreateUser(user users.User) (users.User, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -35,45 +89,359 @@ func (r *Repository) CreateUser(user users.User) users.User {
 	}
 
 	user.ID = id
+	if err := r.storage.SaveUser(user); err != nil {
+		return users.User{}, err
+	}
+
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *Repository) GetUser(userID string) (users.User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	user, contains := r.users[userID]
+	if !contains {
+		return users.User{}, users.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// UpdateUser replaces the existing user with the same ID.
+func (r *Repository) UpdateUser(user users.User) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.isUserExists(user.ID) {
+		return users.ErrUserNotFound
+	}
+	if err := r.storage.SaveUser(user); err != nil {
+		return err
+	}
+
 	r.users[user.ID] = user
-	return user
+	return nil
 }
 
-func (r *Repository) CreateToken(userID string) string {
+// ResolveIdentity returns the user linked to the account of the identity provider,
+// a new user with the name is created and linked on the first login.
+func (r *Repository) ResolveIdentity(issuer string, subject string, name string) (users.User, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	accessToken := idutils.GenerateID()
-	for r.isAccessTokenExists(accessToken) {
-		accessToken = idutils.GenerateID()
+	key := identityKey{issuer: issuer, subject: subject}
+	if identity, contains := r.identities[key]; contains {
+		if user, contains := r.users[identity.UserID]; contains {
+			return user, nil
+		}
 	}
 
-	r.accessTokens[accessToken] = userID
-	return accessToken
+	id := idutils.GenerateID()
+	for r.isUserExists(id) {
+		id = idutils.GenerateID()
+	}
+
+	user := users.User{ID: id, Name: name}
+	identity := users.Identity{Issuer: issuer, Subject: subject, UserID: user.ID}
+	if err := r.storage.SaveUser(user); err != nil {
+		return users.User{}, err
+	}
+	if err := r.storage.SaveIdentity(identity); err != nil {
+		return users.User{}, err
+	}
+
+	r.users[user.ID] = user
+	r.identities[key] = identity
+	return user, nil
+}
+
+// HasIdentity checks whether the user is linked to an account of an identity provider.
+func (r *Repository) HasIdentity(userID string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateSession issues a new access token of the user which is valid until the expiration time.
+func (r *Repository) CreateSession(userID string, expiresAt time.Time) (users.Session, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	id := idutils.GenerateID()
+	for r.isSessionExists(id) {
+		id = idutils.GenerateID()
+	}
+
+	session := users.Session{
+		ID:          id,
+		UserID:      userID,
+		AccessToken: r.createAccessToken(),
+		CreatedAt:   time.Now(),
+		ExpiresAt:   expiresAt,
+	}
+	if err := r.storage.SaveSession(session); err != nil {
+		return users.Session{}, err
+	}
+
+	r.putSession(session)
+	return session, nil
+}
+
+// RefreshSession replaces the access token of the session with a new one valid until the expiration time,
+// the old access token stops working.
+func (r *Repository) RefreshSession(accessToken string, expiresAt time.Time) (users.Session, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	session, err := r.getSession(accessToken, time.Now())
+	if err != nil {
+		return users.Session{}, err
+	}
+
+	session.AccessToken = r.createAccessToken()
+	session.ExpiresAt = expiresAt
+	if err := r.storage.SaveSession(session); err != nil {
+		return users.Session{}, err
+	}
+
+	delete(r.accessTokens, accessToken)
+	r.putSession(session)
+	return session, nil
+}
+
+func (r *Repository) ResolveSession(accessToken string) (users.Session, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.getSession(accessToken, time.Now())
 }
 
 func (r *Repository) ResolveUserByAccessToken(accessToken string) (users.User, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	userID, contains := r.accessTokens[accessToken]
-	if !contains {
-		return users.User{}, ErrAccessTokenNotFound
+	session, err := r.getSession(accessToken, time.Now())
+	if err != nil {
+		return users.User{}, err
 	}
 
-	user, contains := r.users[userID]
+	user, contains := r.users[session.UserID]
 	if !contains {
-		return users.User{}, ErrAccessTokenNotFound
+		return users.User{}, users.ErrAccessTokenNotFound
 	}
 
 	return user, nil
 }
 
+// GetSessions returns the sessions of the user ordered by the creation time.
+func (r *Repository) GetSessions(userID string) []users.Session {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var sessions []users.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b users.Session) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return sessions
+}
+
+// DeleteSession revokes the session of the user.
+func (r *Repository) DeleteSession(userID string, sessionID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	session, contains := r.sessions[sessionID]
+	if !contains || session.UserID != userID {
+		return users.ErrSessionNotFound
+	}
+
+	return r.deleteSessions([]string{sessionID})
+}
+
+// DeleteSessions revokes all sessions of the user.
+func (r *Repository) DeleteSessions(userID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var sessionIDs []string
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			sessionIDs = append(sessionIDs, session.ID)
+		}
+	}
+	return r.deleteSessions(sessionIDs)
+}
+
+// DeleteExpiredSessions deletes sessions which expired before the time.
+func (r *Repository) DeleteExpiredSessions(now time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var sessionIDs []string
+	for _, session := range r.sessions {
+		if !now.Before(session.ExpiresAt) {
+			sessionIDs = append(sessionIDs, session.ID)
+		}
+	}
+	return r.deleteSessions(sessionIDs)
+}
+
+// DeleteUser deletes the user with its identities and revokes all sessions of the user.
+func (r *Repository) DeleteUser(userID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.storage.DeleteUser(userID); err != nil {
+		return err
+	}
+
+	delete(r.users, userID)
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			r.removeSession(session)
+		}
+	}
+	for key, identity := range r.identities {
+		if identity.UserID == userID {
+			delete(r.identities, key)
+		}
+	}
+	return nil
+}
+
+// RevokeUser rejects stateless access tokens of the user until the time.
+func (r *Repository) RevokeUser(userID string, expiresAt time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.storage.SaveRevocation(users.Revocation{UserID: userID, ExpiresAt: expiresAt}); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for id, revocationExpiresAt := range r.revocations {
+		if !now.Before(revocationExpiresAt) {
+			delete(r.revocations, id)
+		}
+	}
+	r.revocations[userID] = expiresAt
+	return nil
+}
+
+// IsUserRevoked reports whether stateless access tokens of the user are rejected. The storage is asked
+// if the repository doesn't know the revocation, it could be made by another instance sharing the storage.
+func (r *Repository) IsUserRevoked(userID string, now time.Time) (bool, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if expiresAt, contains := r.revocations[userID]; contains && now.Before(expiresAt) {
+		return true, nil
+	}
+	return r.storage.IsRevoked(userID, now)
+}
+
+// Export returns all users, sessions and identities of the repository.
+func (r *Repository) Export() ([]users.User, []users.Session, []users.Identity) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	usersList := make([]users.User, 0, len(r.users))
+	for _, user := range r.users {
+		usersList = append(usersList, user)
+	}
+	sessions := make([]users.Session, 0, len(r.sessions))
+	for _, session := range r.sessions {
+		sessions = append(sessions, session)
+	}
+	identities := make([]users.Identity, 0, len(r.identities))
+	for _, identity := range r.identities {
+		identities = append(identities, identity)
+	}
+	return usersList, sessions, identities
+}
+
+// ExportRevocations returns revocations of the repository which haven't expired yet.
+func (r *Repository) ExportRevocations() []users.Revocation {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	now := time.Now()
+	revocations := make([]users.Revocation, 0, len(r.revocations))
+	for userID, expiresAt := range r.revocations {
+		if now.Before(expiresAt) {
+			revocations = append(revocations, users.Revocation{UserID: userID, ExpiresAt: expiresAt})
+		}
+	}
+	return revocations
+}
+
+func (r *Repository) getSession(accessToken string, now time.Time) (users.Session, error) {
+	sessionID, contains := r.accessTokens[accessToken]
+	if !contains {
+		return users.Session{}, users.ErrAccessTokenNotFound
+	}
+
+	session := r.sessions[sessionID]
+	if !now.Before(session.ExpiresAt) {
+		return users.Session{}, users.ErrAccessTokenExpired
+	}
+
+	return session, nil
+}
+
+func (r *Repository) createAccessToken() string {
+	accessToken := idutils.GenerateID()
+	for r.isAccessTokenExists(accessToken) {
+		accessToken = idutils.GenerateID()
+	}
+	return accessToken
+}
+
+func (r *Repository) deleteSessions(sessionIDs []string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	if err := r.storage.DeleteSessions(sessionIDs); err != nil {
+		return err
+	}
+	for _, sessionID := range sessionIDs {
+		r.removeSession(r.sessions[sessionID])
+	}
+	return nil
+}
+
+func (r *Repository) putSession(session users.Session) {
+	r.sessions[session.ID] = session
+	r.accessTokens[session.AccessToken] = session.ID
+}
+
+func (r *Repository) removeSession(session users.Session) {
+	delete(r.sessions, session.ID)
+	delete(r.accessTokens, session.AccessToken)
+}
+
 func (r *Repository) isUserExists(id string) bool {
 	_, contains := r.users[id]
 	return contains
 }
 
+func (r *Repository) isSessionExists(id string) bool {
+	_, contains := r.sessions[id]
+	return contains
+}
+
 func (r *Repository) isAccessTokenExists(accessToken string) bool {
 	_, contains := r.accessTokens[accessToken]
 	return contains
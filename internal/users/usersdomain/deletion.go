@@ -0,0 +1,49 @@
+package usersdomain
+
+import (
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+)
+
+// DeletionReport describes the data removed with the user.
+type DeletionReport struct {
+	UserID           string
+	RevokedSessions  int
+	UnlinkedIdentity bool
+	Rooms            rooms.Erasure
+}
+
+// Delete erases the user: the user is removed from rooms and anonymized in their history,
+// the rooms owned by the user are passed to other players or deleted, the sessions are revoked
+// and the activity is cleared. Stateless access tokens of the user are revoked until all of them expire.
+func (s *Service) Delete(userID string) (DeletionReport, error) {
+	if _, err := s.usersRepository.GetUser(userID); err != nil {
+		return DeletionReport{}, err
+	}
+
+	report := DeletionReport{UserID: userID, UnlinkedIdentity: s.usersRepository.HasIdentity(userID)}
+	if s.tokenCodec == nil {
+		report.RevokedSessions = len(s.usersRepository.GetSessions(userID))
+	}
+
+	erasure, err := s.roomsRepository.EraseUser(userID)
+	report.Rooms = erasure
+	for _, roomID := range erasure.DeletedRooms {
+		s.activityRepository.DeleteRoomActivity(roomID)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	if s.tokenCodec != nil {
+		if err := s.usersRepository.RevokeUser(userID, time.Now().Add(s.tokenTTL)); err != nil {
+			return report, err
+		}
+	}
+	if err := s.usersRepository.DeleteUser(userID); err != nil {
+		return report, err
+	}
+	s.activityRepository.DeleteAllUserActivity(userID)
+	return report, nil
+}
@@ -0,0 +1,98 @@
+package usersdomain
+
+import (
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydata"
+	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdata"
+	"aleksandersh.github.io/planning-poker-server/internal/storage/sqlitestorage"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+	"aleksandersh.github.io/planning-poker-server/internal/users/usersdata"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/jwtutils"
+)
+
+func newTestJWTService(t *testing.T, usersRepository Repository) *Service {
+	t.Helper()
+	secret := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	keys, err := jwtutils.ParseKeys("test:HS256:" + secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec, err := jwtutils.NewCodec(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewService(usersRepository, activitydata.NewRepository(), roomsdata.NewRepo(), time.Hour, codec)
+}
+
+func assertTokenRejected(t *testing.T, s *Service, accessToken string) {
+	t.Helper()
+	if _, err := s.ResolveUserByAccessToken(accessToken); !errors.Is(err, users.ErrAccessTokenNotFound) {
+		t.Errorf("ResolveUserByAccessToken() error = %v, want %v", err, users.ErrAccessTokenNotFound)
+	}
+	if _, err := s.ResolveSession(accessToken); !errors.Is(err, users.ErrAccessTokenNotFound) {
+		t.Errorf("ResolveSession() error = %v, want %v", err, users.ErrAccessTokenNotFound)
+	}
+	if _, err := s.RefreshToken(accessToken); !errors.Is(err, users.ErrAccessTokenNotFound) {
+		t.Errorf("RefreshToken() error = %v, want %v", err, users.ErrAccessTokenNotFound)
+	}
+}
+
+func TestSignedTokenOfDeletedUserIsRejected(t *testing.T) {
+	s := newTestJWTService(t, usersdata.NewRepo())
+	user, session, err := s.Add("Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, otherSession, err := s.Add("Bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Delete(user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	assertTokenRejected(t, s, session.AccessToken)
+	if resolved, err := s.ResolveUserByAccessToken(otherSession.AccessToken); err != nil || resolved.ID != other.ID {
+		t.Errorf("ResolveUserByAccessToken() of another user = %q, %v, want %q", resolved.ID, err, other.ID)
+	}
+}
+
+// TestSignedTokenIsRejectedByInstancesSharingStorage deletes the user by one instance, the other one
+// has loaded the user before and still keeps it.
+func TestSignedTokenIsRejectedByInstancesSharingStorage(t *testing.T) {
+	db, err := sqlitestorage.Open(filepath.Join(t.TempDir(), "poker.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	newInstance := func() *Service {
+		repository, err := usersdata.NewPersistentRepo(sqlitestorage.NewUsersStorage(db))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return newTestJWTService(t, repository)
+	}
+
+	first := newInstance()
+	user, session, err := first.Add("Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := newInstance()
+	if _, err := second.ResolveUserByAccessToken(session.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Delete(user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	assertTokenRejected(t, second, session.AccessToken)
+	if _, err := newInstance().ResolveUserByAccessToken(session.AccessToken); !errors.Is(err, users.ErrAccessTokenNotFound) {
+		t.Errorf("ResolveUserByAccessToken() of a restarted instance error = %v, want %v", err, users.ErrAccessTokenNotFound)
+	}
+}
@@ -0,0 +1,68 @@
+package usersdomain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/jwtutils"
+)
+
+// tokenClaims are claims of stateless access tokens. The session ID is kept on refresh,
+// so the tokens issued for the same registration are recognized as a single session.
+type tokenClaims struct {
+	Subject   string `json:"sub"`
+	Name      string `json:"name"`
+	SessionID string `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func (s *Service) signToken(user users.User, sessionID string) (users.Session, error) {
+	now := time.Now()
+	claims := tokenClaims{
+		Subject:   user.ID,
+		Name:      user.Name,
+		SessionID: sessionID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.tokenTTL).Unix(),
+	}
+	accessToken, err := s.tokenCodec.Sign(claims)
+	if err != nil {
+		return users.Session{}, err
+	}
+	return mapClaimsToSession(claims, accessToken), nil
+}
+
+func (s *Service) verifyToken(accessToken string) (tokenClaims, error) {
+	var claims tokenClaims
+	err := s.tokenCodec.Verify(accessToken, &claims)
+	if errors.Is(err, jwtutils.ErrTokenExpired) {
+		return tokenClaims{}, users.ErrAccessTokenExpired
+	}
+	if err != nil {
+		return tokenClaims{}, fmt.Errorf("%w: %w", users.ErrAccessTokenNotFound, err)
+	}
+	if claims.Subject == "" || claims.ExpiresAt == 0 {
+		return tokenClaims{}, users.ErrAccessTokenNotFound
+	}
+	revoked, err := s.usersRepository.IsUserRevoked(claims.Subject, time.Now())
+	if err != nil {
+		return tokenClaims{}, err
+	}
+	if revoked {
+		return tokenClaims{}, users.ErrAccessTokenNotFound
+	}
+	return claims, nil
+}
+
+func mapClaimsToSession(claims tokenClaims, accessToken string) users.Session {
+	return users.Session{
+		ID:          claims.SessionID,
+		UserID:      claims.Subject,
+		AccessToken: accessToken,
+		CreatedAt:   time.Unix(claims.IssuedAt, 0),
+		ExpiresAt:   time.Unix(claims.ExpiresAt, 0),
+	}
+}
@@ -0,0 +1,90 @@
+package usersdomain
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/oidc"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/idutils"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/signutils"
+)
+
+const (
+	loginStateType = "oidc_state"
+	loginStateTTL  = 10 * time.Minute
+)
+
+// loginStateClaims are signed into the state of the authorization request, so the callback is accepted
+// only for logins started by the server and the nonce of the ID token is known without a lookup.
+// The state is bound to the browser which started the login by the hash of a value kept in its cookie.
+type loginStateClaims struct {
+	Type        string `json:"typ"`
+	Nonce       string `json:"nonce"`
+	BindingHash string `json:"bnd"`
+	ExpiresAt   int64  `json:"exp"`
+}
+
+// LoginService signs in users by the authorization code flow of an OpenID Connect provider.
+type LoginService struct {
+	usersService *Service
+	provider     *oidc.Provider
+	stateSigner  *signutils.Signer
+}
+
+func NewLoginService(usersService *Service, provider *oidc.Provider, stateSigner *signutils.Signer) *LoginService {
+	return &LoginService{usersService: usersService, provider: provider, stateSigner: stateSigner}
+}
+
+// Start returns a URL of the provider login page, the provider redirects back with a code to complete the login.
+// The binding must be kept by the browser and given back to complete the login.
+func (ls *LoginService) Start(ctx context.Context) (url string, binding string, err error) {
+	binding = idutils.GenerateID()
+	claims := loginStateClaims{
+		Type:        loginStateType,
+		Nonce:       idutils.GenerateID(),
+		BindingHash: hashLoginBinding(binding),
+		ExpiresAt:   time.Now().Add(loginStateTTL).Unix(),
+	}
+	state, err := ls.stateSigner.Sign(claims)
+	if err != nil {
+		return "", "", err
+	}
+	url, err = ls.provider.AuthCodeURL(ctx, state, claims.Nonce)
+	return url, binding, err
+}
+
+// Complete redeems the authorization code and signs in the user linked to the subject of the ID token.
+// The binding must be the one given by Start for the state, so a state can't be used in another browser.
+func (ls *LoginService) Complete(ctx context.Context, code string, state string, binding string) (users.User, users.Session, error) {
+	var claims loginStateClaims
+	if err := ls.stateSigner.Verify(state, &claims); err != nil {
+		return users.User{}, users.Session{}, users.ErrInvalidLoginState
+	}
+	if claims.Type != loginStateType || !time.Now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return users.User{}, users.Session{}, users.ErrInvalidLoginState
+	}
+	if binding == "" || subtle.ConstantTimeCompare([]byte(hashLoginBinding(binding)), []byte(claims.BindingHash)) != 1 {
+		return users.User{}, users.Session{}, users.ErrInvalidLoginState
+	}
+
+	identity, err := ls.provider.Exchange(ctx, code, claims.Nonce)
+	if err != nil {
+		return users.User{}, users.Session{}, fmt.Errorf("%w: %w", users.ErrLoginFailed, err)
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = identity.Subject
+	}
+	return ls.usersService.AddIdentity(identity.Issuer, identity.Subject, name)
+}
+
+func hashLoginBinding(binding string) string {
+	hash := sha256.Sum256([]byte(binding))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,114 @@
+package usersdomain
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydata"
+	"aleksandersh.github.io/planning-poker-server/internal/oidc"
+	"aleksandersh.github.io/planning-poker-server/internal/oidc/oidctest"
+	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdata"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+	"aleksandersh.github.io/planning-poker-server/internal/users/usersdata"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/signutils"
+)
+
+const testRedirectURL = "http://localhost/v1/auth/oidc/callback"
+
+func newTestLoginService(t *testing.T) (*LoginService, *Service) {
+	t.Helper()
+	fake := oidctest.NewProvider()
+	t.Cleanup(fake.Close)
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       fake.Issuer(),
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  testRedirectURL,
+	})
+	service := NewService(usersdata.NewRepo(), activitydata.NewRepository(), roomsdata.NewRepo(), time.Hour, nil)
+	return NewLoginService(service, provider, signutils.NewSigner(signutils.GenerateKey())), service
+}
+
+// login runs the flow in the browser: the provider logs in the subject and redirects back with the code and state.
+func login(t *testing.T, ls *LoginService, subject string) (code string, state string, binding string) {
+	t.Helper()
+	authURL, binding, err := ls.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	query.Set("login_hint", subject)
+	parsed.RawQuery = query.Encode()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	response, err := client.Get(parsed.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	callback, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return callback.Query().Get("code"), callback.Query().Get("state"), binding
+}
+
+func TestLoginMapsSubjectToUser(t *testing.T) {
+	ls, service := newTestLoginService(t)
+
+	code, state, binding := login(t, ls, "alice-sub")
+	alice, session, err := ls.Complete(context.Background(), code, state, binding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Name != "User alice-sub" {
+		t.Errorf("alice.Name = %q, want %q", alice.Name, "User alice-sub")
+	}
+	resolved, err := service.ResolveUserByAccessToken(session.AccessToken)
+	if err != nil || resolved.ID != alice.ID {
+		t.Errorf("ResolveUserByAccessToken() = %v, %v, want %v", resolved.ID, err, alice.ID)
+	}
+
+	code, state, binding = login(t, ls, "alice-sub")
+	again, _, err := ls.Complete(context.Background(), code, state, binding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != alice.ID {
+		t.Errorf("second login user = %q, want %q", again.ID, alice.ID)
+	}
+
+	code, state, binding = login(t, ls, "bob-sub")
+	bob, _, err := ls.Complete(context.Background(), code, state, binding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bob.ID == alice.ID {
+		t.Errorf("login of another subject gave the same user %q", bob.ID)
+	}
+}
+
+func TestLoginRejectsUnboundState(t *testing.T) {
+	ls, _ := newTestLoginService(t)
+
+	code, state, _ := login(t, ls, "alice-sub")
+	_, _, otherBinding := login(t, ls, "mallory-sub")
+	for _, binding := range []string{"", otherBinding} {
+		if _, _, err := ls.Complete(context.Background(), code, state, binding); !errors.Is(err, users.ErrInvalidLoginState) {
+			t.Errorf("Complete() with binding %q error = %v, want %v", binding, err, users.ErrInvalidLoginState)
+		}
+	}
+
+	_, _, binding := login(t, ls, "alice-sub")
+	if _, _, err := ls.Complete(context.Background(), code, "forged", binding); !errors.Is(err, users.ErrInvalidLoginState) {
+		t.Errorf("Complete() with forged state error = %v, want %v", err, users.ErrInvalidLoginState)
+	}
+}
@@ -0,0 +1,89 @@
+package usersdomain
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+)
+
+const (
+	maxAvatarURLLength   = 2048
+	maxAvatarEmojiLength = 8
+)
+
+var colorPattern = regexp.MustCompile("^[0-9A-F]{6}$")
+
+// ProfileUpdate contains the changed fields of the profile, nil fields are kept. An empty color
+// or avatar resets it.
+type ProfileUpdate struct {
+	Name   *string
+	Color  *string
+	Avatar *string
+}
+
+func (s *Service) GetProfile(userID string) (users.User, error) {
+	return s.usersRepository.GetUser(userID)
+}
+
+// UpdateProfile changes the profile of the user, the changes are applied to the user in every room.
+func (s *Service) UpdateProfile(userID string, update ProfileUpdate) (users.User, error) {
+	user, err := s.usersRepository.GetUser(userID)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if len(name) == 0 {
+			return users.User{}, users.ErrInvalidProfile
+		}
+		user.Name = name
+	}
+	if update.Color != nil {
+		color := strings.ToUpper(strings.TrimPrefix(*update.Color, "#"))
+		if len(color) > 0 && !colorPattern.MatchString(color) {
+			return users.User{}, users.ErrInvalidProfile
+		}
+		user.Color = color
+	}
+	if update.Avatar != nil {
+		avatar := strings.TrimSpace(*update.Avatar)
+		if len(avatar) > 0 && !isAvatarURL(avatar) && !isAvatarEmoji(avatar) {
+			return users.User{}, users.ErrInvalidProfile
+		}
+		user.Avatar = avatar
+	}
+
+	if err := s.usersRepository.UpdateUser(user); err != nil {
+		return users.User{}, err
+	}
+	s.activityRepository.AddUserActivity(user.ID)
+	if err := s.roomsRepository.UpdatePlayer(user); err != nil {
+		return users.User{}, err
+	}
+	return user, nil
+}
+
+func isAvatarURL(avatar string) bool {
+	if len(avatar) > maxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(avatar)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// isAvatarEmoji accepts a few non-ASCII characters, so emoji sequences joined by zero width joiners fit.
+func isAvatarEmoji(avatar string) bool {
+	if utf8.RuneCountInString(avatar) > maxAvatarEmojiLength {
+		return false
+	}
+	for _, r := range avatar {
+		if r < utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package usersdomain
+
+import (
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/rooms"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+)
+
+// Repository stores users and their sessions.
+type Repository interface {
+	CreateUser(user users.User) (users.User, error)
+	GetUser(userID string) (users.User, error)
+	HasIdentity(userID string) bool
+	UpdateUser(user users.User) error
+	DeleteUser(userID string) error
+	ResolveIdentity(issuer string, subject string, name string) (users.User, error)
+	CreateSession(userID string, expiresAt time.Time) (users.Session, error)
+	RefreshSession(accessToken string, expiresAt time.Time) (users.Session, error)
+	ResolveSession(accessToken string) (users.Session, error)
+	ResolveUserByAccessToken(accessToken string) (users.User, error)
+	GetSessions(userID string) []users.Session
+	DeleteSession(userID string, sessionID string) error
+	DeleteSessions(userID string) error
+	RevokeUser(userID string, expiresAt time.Time) error
+	IsUserRevoked(userID string, now time.Time) (bool, error)
+}
+
+// RoomsRepository keeps copies of user profiles in rooms.
+type RoomsRepository interface {
+	UpdatePlayer(user users.User) error
+	EraseUser(userID string) (rooms.Erasure, error)
+}
@@ -1,29 +1,143 @@
 package usersdomain
 
 import (
+	"time"
+
 	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydata"
 	"aleksandersh.github.io/planning-poker-server/internal/users"
-	"aleksandersh.github.io/planning-poker-server/internal/users/usersdata"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/idutils"
+	"aleksandersh.github.io/planning-poker-server/internal/utils/jwtutils"
 )
 
 type Service struct {
-	usersRepository    *usersdata.Repository
+	usersRepository    Repository
 	activityRepository *activitydata.Repository
+	roomsRepository    RoomsRepository
+	// tokenTTL is a lifetime of access tokens, a token is replaced by a new one on refresh.
+	tokenTTL time.Duration
+	// tokenCodec issues stateless signed access tokens instead of sessions kept by the repository if it is set,
+	// such tokens are verified without a session lookup but can't be revoked one by one before they expire,
+	// only tokens of deleted users are rejected by the revocations of the repository.
+	tokenCodec *jwtutils.Codec
 }
 
-func NewService(usersRepository *usersdata.Repository, activityRepository *activitydata.Repository) *Service {
-	return &Service{usersRepository: usersRepository, activityRepository: activityRepository}
+func NewService(
+	usersRepository Repository,
+	activityRepository *activitydata.Repository,
+	roomsRepository RoomsRepository,
+	tokenTTL time.Duration,
+	tokenCodec *jwtutils.Codec,
+) *Service {
+	return &Service{
+		usersRepository:    usersRepository,
+		activityRepository: activityRepository,
+		roomsRepository:    roomsRepository,
+		tokenTTL:           tokenTTL,
+		tokenCodec:         tokenCodec,
+	}
 }
 
-func (s *Service) Add(name string) (users.User, string) {
-	user := users.User{Name: name}
-	user = s.usersRepository.CreateUser(user)
+func (s *Service) Add(name string) (users.User, users.Session, error) {
+	user, err := s.usersRepository.CreateUser(users.User{Name: name})
+	if err != nil {
+		return users.User{}, users.Session{}, err
+	}
 	s.activityRepository.AddUserActivity(user.ID)
-	// todo: users activity watcher
-	accessToken := s.usersRepository.CreateToken(user.ID)
-	return user, accessToken
+	session, err := s.createSession(user)
+	if err != nil {
+		return users.User{}, users.Session{}, err
+	}
+	return user, session, nil
+}
+
+// AddIdentity signs in the user of the identity provider account, the user is created on the first login.
+func (s *Service) AddIdentity(issuer string, subject string, name string) (users.User, users.Session, error) {
+	user, err := s.usersRepository.ResolveIdentity(issuer, subject, name)
+	if err != nil {
+		return users.User{}, users.Session{}, err
+	}
+	s.activityRepository.AddUserActivity(user.ID)
+	session, err := s.createSession(user)
+	if err != nil {
+		return users.User{}, users.Session{}, err
+	}
+	return user, session, nil
 }
 
 func (s *Service) ResolveUserByAccessToken(accessToken string) (users.User, error) {
+	if s.tokenCodec != nil {
+		claims, err := s.verifyToken(accessToken)
+		if err != nil {
+			return users.User{}, err
+		}
+		return users.User{ID: claims.Subject, Name: claims.Name}, nil
+	}
 	return s.usersRepository.ResolveUserByAccessToken(accessToken)
 }
+
+func (s *Service) ResolveSession(accessToken string) (users.Session, error) {
+	if s.tokenCodec != nil {
+		claims, err := s.verifyToken(accessToken)
+		if err != nil {
+			return users.Session{}, err
+		}
+		return mapClaimsToSession(claims, accessToken), nil
+	}
+	return s.usersRepository.ResolveSession(accessToken)
+}
+
+// RefreshToken issues a new access token for the session of a valid one.
+func (s *Service) RefreshToken(accessToken string) (users.Session, error) {
+	var session users.Session
+	var err error
+	if s.tokenCodec != nil {
+		session, err = s.refreshSignedToken(accessToken)
+	} else {
+		session, err = s.usersRepository.RefreshSession(accessToken, time.Now().Add(s.tokenTTL))
+	}
+	if err == nil {
+		s.activityRepository.AddUserActivity(session.UserID)
+	}
+	return session, err
+}
+
+func (s *Service) GetSessions(userID string) ([]users.Session, error) {
+	if s.tokenCodec != nil {
+		return nil, users.ErrSessionsNotSupported
+	}
+	return s.usersRepository.GetSessions(userID), nil
+}
+
+func (s *Service) RevokeSession(userID string, sessionID string) error {
+	if s.tokenCodec != nil {
+		return users.ErrSessionsNotSupported
+	}
+	return s.usersRepository.DeleteSession(userID, sessionID)
+}
+
+func (s *Service) RevokeSessions(userID string) error {
+	if s.tokenCodec != nil {
+		return users.ErrSessionsNotSupported
+	}
+	return s.usersRepository.DeleteSessions(userID)
+}
+
+func (s *Service) createSession(user users.User) (users.Session, error) {
+	if s.tokenCodec != nil {
+		return s.signToken(user, idutils.GenerateID())
+	}
+	return s.usersRepository.CreateSession(user.ID, time.Now().Add(s.tokenTTL))
+}
+
+// refreshSignedToken signs a new token with the current name of the user, tokens of deleted users aren't refreshed.
+func (s *Service) refreshSignedToken(accessToken string) (users.Session, error) {
+	claims, err := s.verifyToken(accessToken)
+	if err != nil {
+		return users.Session{}, err
+	}
+	user, err := s.usersRepository.GetUser(claims.Subject)
+	if err != nil {
+		return users.Session{}, users.ErrAccessTokenNotFound
+	}
+	return s.signToken(user, claims.SessionID)
+}
@@ -0,0 +1,145 @@
+package usersdomain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"aleksandersh.github.io/planning-poker-server/internal/activity/activitydata"
+	"aleksandersh.github.io/planning-poker-server/internal/rooms/roomsdata"
+	"aleksandersh.github.io/planning-poker-server/internal/users"
+	"aleksandersh.github.io/planning-poker-server/internal/users/usersdata"
+)
+
+func TestSessions(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokenTTL time.Duration
+		// use changes the session and returns the access token which is checked afterwards.
+		use     func(t *testing.T, s *Service, session users.Session) string
+		wantErr error
+	}{
+		{
+			name:     "valid token",
+			tokenTTL: time.Hour,
+			use: func(t *testing.T, s *Service, session users.Session) string {
+				return session.AccessToken
+			},
+		},
+		{
+			name:     "refreshed token",
+			tokenTTL: time.Hour,
+			use: func(t *testing.T, s *Service, session users.Session) string {
+				refreshed, err := s.RefreshToken(session.AccessToken)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if refreshed.ID != session.ID || refreshed.AccessToken == session.AccessToken {
+					t.Errorf("refreshed session %q with token %q, want session %q with a new token", refreshed.ID, refreshed.AccessToken, session.ID)
+				}
+				return refreshed.AccessToken
+			},
+		},
+		{
+			name:     "token replaced by refresh",
+			tokenTTL: time.Hour,
+			use: func(t *testing.T, s *Service, session users.Session) string {
+				if _, err := s.RefreshToken(session.AccessToken); err != nil {
+					t.Fatal(err)
+				}
+				return session.AccessToken
+			},
+			wantErr: users.ErrAccessTokenNotFound,
+		},
+		{
+			name:     "revoked session",
+			tokenTTL: time.Hour,
+			use: func(t *testing.T, s *Service, session users.Session) string {
+				if err := s.RevokeSession(session.UserID, session.ID); err != nil {
+					t.Fatal(err)
+				}
+				return session.AccessToken
+			},
+			wantErr: users.ErrAccessTokenNotFound,
+		},
+		{
+			name:     "refreshed token of revoked session",
+			tokenTTL: time.Hour,
+			use: func(t *testing.T, s *Service, session users.Session) string {
+				refreshed, err := s.RefreshToken(session.AccessToken)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := s.RevokeSession(session.UserID, session.ID); err != nil {
+					t.Fatal(err)
+				}
+				return refreshed.AccessToken
+			},
+			wantErr: users.ErrAccessTokenNotFound,
+		},
+		{
+			name:     "all sessions revoked",
+			tokenTTL: time.Hour,
+			use: func(t *testing.T, s *Service, session users.Session) string {
+				if err := s.RevokeSessions(session.UserID); err != nil {
+					t.Fatal(err)
+				}
+				return session.AccessToken
+			},
+			wantErr: users.ErrAccessTokenNotFound,
+		},
+		{
+			name:     "expired token",
+			tokenTTL: 0,
+			use: func(t *testing.T, s *Service, session users.Session) string {
+				return session.AccessToken
+			},
+			wantErr: users.ErrAccessTokenExpired,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewService(usersdata.NewRepo(), activitydata.NewRepository(), roomsdata.NewRepo(), test.tokenTTL, nil)
+			user, session, err := s.Add("Alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			accessToken := test.use(t, s, session)
+
+			resolved, err := s.ResolveUserByAccessToken(accessToken)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("ResolveUserByAccessToken() error = %v, want %v", err, test.wantErr)
+			}
+			if err == nil && resolved.ID != user.ID {
+				t.Errorf("ResolveUserByAccessToken() = %q, want %q", resolved.ID, user.ID)
+			}
+			if _, err := s.RefreshToken(accessToken); !errors.Is(err, test.wantErr) {
+				t.Errorf("RefreshToken() error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestSessionExpiresAfterTTL(t *testing.T) {
+	tokenTTL := time.Hour
+	s := NewService(usersdata.NewRepo(), activitydata.NewRepository(), roomsdata.NewRepo(), tokenTTL, nil)
+	before := time.Now()
+	_, session, err := s.Add("Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.ExpiresAt.Before(before.Add(tokenTTL)) || session.ExpiresAt.After(time.Now().Add(tokenTTL)) {
+		t.Errorf("ExpiresAt = %v, want the TTL after the creation", session.ExpiresAt)
+	}
+
+	refreshed, err := s.RefreshToken(session.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.ExpiresAt.Before(session.ExpiresAt) {
+		t.Errorf("refreshed ExpiresAt = %v, want it extended from %v", refreshed.ExpiresAt, session.ExpiresAt)
+	}
+	if !refreshed.CreatedAt.Equal(session.CreatedAt) {
+		t.Errorf("refreshed CreatedAt = %v, want the creation of the session %v", refreshed.CreatedAt, session.CreatedAt)
+	}
+}
@@ -0,0 +1,180 @@
+package jwtutils
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// Key is a named key of the token signature, the name is written to the "kid" header of tokens.
+type Key struct {
+	ID        string
+	Algorithm string
+	secret    []byte
+	private   ed25519.PrivateKey
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+type expiration struct {
+	ExpiresAt int64 `json:"exp"`
+}
+
+// Codec signs JSON Web Tokens with the first key and verifies them with any of the keys,
+// so keys are rotated by adding a new key first and removing the old one once its tokens expire.
+type Codec struct {
+	signingKey Key
+	keys       map[string]Key
+}
+
+func NewCodec(keys []Key) (*Codec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no keys")
+	}
+	c := &Codec{signingKey: keys[0], keys: make(map[string]Key, len(keys))}
+	for _, key := range keys {
+		if _, contains := c.keys[key.ID]; contains {
+			return nil, fmt.Errorf("duplicate key %q", key.ID)
+		}
+		c.keys[key.ID] = key
+	}
+	return c, nil
+}
+
+// ParseKeys parses comma separated keys in the "<kid>:<algorithm>:<base64 key>" format. HS256 keys are secrets,
+// EdDSA keys are Ed25519 seeds or private keys.
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	for _, item := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("key %q must be <kid>:<algorithm>:<base64 key>", item)
+		}
+		material, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key %q: %w", parts[0], err)
+		}
+		key := Key{ID: parts[0], Algorithm: parts[1]}
+		switch key.Algorithm {
+		case AlgorithmHS256:
+			if len(material) < 32 {
+				return nil, fmt.Errorf("key %q must be at least 32 bytes", key.ID)
+			}
+			key.secret = material
+		case AlgorithmEdDSA:
+			switch len(material) {
+			case ed25519.SeedSize:
+				key.private = ed25519.NewKeyFromSeed(material)
+			case ed25519.PrivateKeySize:
+				key.private = ed25519.PrivateKey(material)
+			default:
+				return nil, fmt.Errorf("key %q must be an Ed25519 seed or private key", key.ID)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported algorithm %q of key %q", key.Algorithm, key.ID)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Sign encodes the claims as JSON and returns a token signed with the first key.
+func (c *Codec) Sign(claims any) (string, error) {
+	encodedHeader, err := encodeSegment(header{Algorithm: c.signingKey.Algorithm, Type: "JWT", KeyID: c.signingKey.ID})
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := encodedHeader + "." + encodedClaims
+	return signed + "." + base64.RawURLEncoding.EncodeToString(c.signingKey.sign(signed)), nil
+}
+
+// Verify checks the signature and the "exp" claim of the token and decodes its claims.
+// The algorithm of the header must match the key, so a token can't choose a weaker verification.
+func (c *Codec) Verify(token string, claims any) error {
+	encodedHeader, rest, found := strings.Cut(token, ".")
+	if !found {
+		return ErrInvalidToken
+	}
+	encodedClaims, encodedSignature, found := strings.Cut(rest, ".")
+	if !found {
+		return ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeSegment(encodedHeader, &h); err != nil {
+		return ErrInvalidToken
+	}
+	key, contains := c.keys[h.KeyID]
+	if !contains || key.Algorithm != h.Algorithm {
+		return ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !key.verify(encodedHeader+"."+encodedClaims, signature) {
+		return ErrInvalidToken
+	}
+
+	var exp expiration
+	if err := decodeSegment(encodedClaims, &exp); err != nil {
+		return ErrInvalidToken
+	}
+	if exp.ExpiresAt != 0 && !time.Now().Before(time.Unix(exp.ExpiresAt, 0)) {
+		return ErrTokenExpired
+	}
+	return decodeSegment(encodedClaims, claims)
+}
+
+func (k Key) sign(signed string) []byte {
+	if k.Algorithm == AlgorithmEdDSA {
+		return ed25519.Sign(k.private, []byte(signed))
+	}
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil)
+}
+
+func (k Key) verify(signed string, signature []byte) bool {
+	if k.Algorithm == AlgorithmEdDSA {
+		return ed25519.Verify(k.private.Public().(ed25519.PublicKey), []byte(signed), signature)
+	}
+	return hmac.Equal(signature, k.sign(signed))
+}
+
+func encodeSegment(value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeSegment(encoded string, value any) error {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
@@ -0,0 +1,195 @@
+package jwtutils
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testClaims struct {
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func newTestCodec(t *testing.T, spec string) *Codec {
+	t.Helper()
+	keys, err := ParseKeys(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec, err := NewCodec(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return codec
+}
+
+func testKeySpec(id string, algorithm string, material string) string {
+	return id + ":" + algorithm + ":" + base64.StdEncoding.EncodeToString([]byte(material))
+}
+
+// forgeToken signs the header and the claims with the function, it gives tokens no codec would sign.
+func forgeToken(t *testing.T, h header, claims any, sign func(signed string) []byte) string {
+	t.Helper()
+	encodedHeader, err := encodeSegment(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedClaims, err := encodeSegment(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := encodedHeader + "." + encodedClaims
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(signed))
+}
+
+func TestVerify(t *testing.T) {
+	oldKey := testKeySpec("old", AlgorithmEdDSA, "old-ed25519-seed-of-32-bytes-len")
+	newKey := testKeySpec("new", AlgorithmEdDSA, "new-ed25519-seed-of-32-bytes-len")
+	oldCodec := newTestCodec(t, oldKey)
+	rotatedCodec := newTestCodec(t, newKey+","+oldKey)
+	newCodec := newTestCodec(t, newKey)
+	validClaims := testClaims{Subject: "user", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+	sign := func(codec *Codec, claims testClaims) string {
+		token, err := codec.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	// the public key of an EdDSA key is known to everyone, a verifier trusting the header would accept it as an HS256 secret
+	publicKey := rotatedCodec.keys["new"].private.Public().(ed25519.PublicKey)
+	hs256WithPublicKey := func(signed string) []byte {
+		mac := hmac.New(sha256.New, publicKey)
+		mac.Write([]byte(signed))
+		return mac.Sum(nil)
+	}
+
+	tests := []struct {
+		name    string
+		codec   *Codec
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "token of the signing key",
+			codec: rotatedCodec,
+			token: sign(rotatedCodec, validClaims),
+		},
+		{
+			name:  "token of the previous key after rotation",
+			codec: rotatedCodec,
+			token: sign(oldCodec, validClaims),
+		},
+		{
+			name:    "token of a removed key",
+			codec:   newCodec,
+			token:   sign(oldCodec, validClaims),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "unknown kid",
+			codec:   rotatedCodec,
+			token:   forgeToken(t, header{Algorithm: AlgorithmEdDSA, Type: "JWT", KeyID: "unknown"}, validClaims, rotatedCodec.keys["new"].sign),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "HS256 token for an EdDSA key",
+			codec:   rotatedCodec,
+			token:   forgeToken(t, header{Algorithm: AlgorithmHS256, Type: "JWT", KeyID: "new"}, validClaims, hs256WithPublicKey),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "alg none",
+			codec:   rotatedCodec,
+			token:   forgeToken(t, header{Algorithm: "none", Type: "JWT", KeyID: "new"}, validClaims, func(string) []byte { return nil }),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "signature of other claims",
+			codec:   rotatedCodec,
+			token:   replaceClaims(t, sign(rotatedCodec, validClaims), testClaims{Subject: "admin", ExpiresAt: validClaims.ExpiresAt}),
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "expired token",
+			codec:   rotatedCodec,
+			token:   sign(rotatedCodec, testClaims{Subject: "user", ExpiresAt: time.Now().Add(-time.Second).Unix()}),
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:    "malformed token",
+			codec:   rotatedCodec,
+			token:   "not-a-token",
+			wantErr: ErrInvalidToken,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var claims testClaims
+			err := test.codec.Verify(test.token, &claims)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, test.wantErr)
+			}
+			if err == nil && claims != validClaims {
+				t.Errorf("Verify() claims = %v, want %v", claims, validClaims)
+			}
+		})
+	}
+}
+
+func TestVerifyHS256(t *testing.T) {
+	codec := newTestCodec(t, testKeySpec("secret", AlgorithmHS256, "0123456789abcdef0123456789abcdef"))
+	other := newTestCodec(t, testKeySpec("secret", AlgorithmHS256, "fedcba9876543210fedcba9876543210"))
+	claims := testClaims{Subject: "user", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	token, err := codec.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var verified testClaims
+	if err := codec.Verify(token, &verified); err != nil || verified != claims {
+		t.Errorf("Verify() = %v, %v, want %v", verified, err, claims)
+	}
+	if err := other.Verify(token, &verified); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() with another secret error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{name: "HS256 and EdDSA keys", spec: testKeySpec("a", AlgorithmHS256, strings.Repeat("s", 32)) + "," + testKeySpec("b", AlgorithmEdDSA, strings.Repeat("e", 32))},
+		{name: "short HS256 secret", spec: testKeySpec("a", AlgorithmHS256, "short"), wantErr: true},
+		{name: "EdDSA key of a wrong size", spec: testKeySpec("a", AlgorithmEdDSA, "short"), wantErr: true},
+		{name: "unsupported algorithm", spec: testKeySpec("a", "none", strings.Repeat("s", 32)), wantErr: true},
+		{name: "missing kid", spec: ":HS256:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("s", 32))), wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseKeys(test.spec); (err != nil) != test.wantErr {
+				t.Errorf("ParseKeys() error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+// replaceClaims gives the token other claims keeping its header and signature.
+func replaceClaims(t *testing.T, token string, claims any) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	encodedClaims, err := encodeSegment(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parts[0] + "." + encodedClaims + "." + parts[2]
+}
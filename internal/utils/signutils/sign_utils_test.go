@@ -0,0 +1,43 @@
+package signutils
+
+import (
+	"errors"
+	"testing"
+)
+
+type testPayload struct {
+	Value string `json:"value"`
+}
+
+func TestVerify(t *testing.T) {
+	signer := NewSigner([]byte("0123456789abcdef0123456789abcdef"))
+	token, err := signer.ForPurpose("first").Sign(testPayload{Value: "value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		signer  *Signer
+		token   string
+		wantErr error
+	}{
+		{name: "same purpose", signer: signer.ForPurpose("first"), token: token},
+		{name: "another purpose", signer: signer.ForPurpose("second"), token: token, wantErr: ErrInvalidSignature},
+		{name: "without a purpose", signer: signer, token: token, wantErr: ErrInvalidSignature},
+		{name: "another key", signer: NewSigner(GenerateKey()).ForPurpose("first"), token: token, wantErr: ErrInvalidSignature},
+		{name: "malformed token", signer: signer.ForPurpose("first"), token: "token", wantErr: ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var payload testPayload
+			err := test.signer.Verify(test.token, &payload)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, test.wantErr)
+			}
+			if err == nil && payload.Value != "value" {
+				t.Errorf("Verify() payload = %v, want the signed one", payload)
+			}
+		})
+	}
+}